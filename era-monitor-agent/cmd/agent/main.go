@@ -6,11 +6,11 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/eracloud/era-monitor-agent/internal/agent"
 	"github.com/eracloud/era-monitor-agent/internal/config"
 	"github.com/eracloud/era-monitor-agent/internal/logger"
-	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
@@ -18,31 +18,10 @@ func main() {
 	configFile := flag.String("config", "config.yaml", "Path to configuration file")
 	flag.Parse()
 
-	// Load Configuration
-	viper.SetConfigFile(*configFile)
-	viper.SetConfigType("yaml")
-	
-	// Set Defaults
-	defaultCfg := config.GetDefaultConfig()
-	// (Viper defaults setting omitted for brevity, relying on struct defaults for now)
-
-	if err := viper.ReadInConfig(); err != nil {
-		// If config file doesn't exist, we'll use defaults but warn
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			panic("Fatal error config file: " + err.Error())
-		}
-	}
-
-	var cfg config.Config
-	if err := viper.Unmarshal(&cfg); err != nil {
-		panic("Unable to decode into struct: " + err.Error())
-	}
-	
-	// If config file was missing, use defaults populated manually if needed, 
-	// but for now we assume the struct zero values or manual override.
-	// A better approach is to merge defaults.
-	if cfg.Server.APIEndpoint == "" {
-		cfg = *defaultCfg
+	// Load Configuration. Anything the file leaves out keeps its default.
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		panic("Fatal error config file: " + err.Error())
 	}
 
 	// Initialize Logger
@@ -52,7 +31,7 @@ func main() {
 	log.Info("Agent initializing...")
 
 	// Create Agent
-	agt := agent.NewAgent(&cfg, log)
+	agt := agent.NewAgent(cfg, log)
 
 	// Context with Cancellation
 	ctx, cancel := context.WithCancel(context.Background())
@@ -62,9 +41,11 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	stopSignal := make(chan os.Signal, 1)
 	go func() {
 		sig := <-sigChan
 		log.Info("Received signal, shutting down...", zap.String("signal", sig.String()))
+		stopSignal <- sig
 		cancel()
 	}()
 
@@ -73,5 +54,20 @@ func main() {
 		log.Fatal("Agent stopped with error", zap.Error(err))
 	}
 
+	// Tell the server this is a planned stop
+	select {
+	case sig := <-stopSignal:
+		timeout := time.Duration(cfg.Agent.Shutdown.TimeoutSeconds) * time.Second
+		if timeout <= 0 {
+			timeout = 5 * time.Second
+		}
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), timeout)
+		if err := agt.NotifyShutdown(shutdownCtx, sig.String()); err != nil {
+			log.Warn("Failed to notify server of shutdown", zap.Error(err))
+		}
+		shutdownCancel()
+	default:
+	}
+
 	log.Info("Agent shutdown complete")
 }
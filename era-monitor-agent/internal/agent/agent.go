@@ -10,9 +10,15 @@ import (
 	"time"
 
 	"github.com/eracloud/era-monitor-agent/internal/api"
+	"github.com/eracloud/era-monitor-agent/internal/collectors/attributes"
+	"github.com/eracloud/era-monitor-agent/internal/collectors/cloud"
 	"github.com/eracloud/era-monitor-agent/internal/collectors/eventlog"
+	"github.com/eracloud/era-monitor-agent/internal/collectors/hostfs"
+	"github.com/eracloud/era-monitor-agent/internal/collectors/inventory"
+	"github.com/eracloud/era-monitor-agent/internal/collectors/packages"
 	"github.com/eracloud/era-monitor-agent/internal/collectors/service"
 	"github.com/eracloud/era-monitor-agent/internal/collectors/system"
+	"github.com/eracloud/era-monitor-agent/internal/collectors/updates"
 	"github.com/eracloud/era-monitor-agent/internal/config"
 	"github.com/go-resty/resty/v2"
 	"go.uber.org/zap"
@@ -23,8 +29,16 @@ type Agent struct {
 	logger            *zap.Logger
 	systemCollector   *system.SystemCollector
 	eventLogCollector *eventlog.Collector
+	attributes        *attributes.Collector
+	cloudCollector    *cloud.Collector
+	inventory         *inventory.Collector
+	packages          *packages.Collector
+	updates           *updates.Collector
 	serviceMonitors   []service.Monitor
 	client            *resty.Client
+	delta             *deltaTracker
+	streams           *streamSet
+	telemetry         *telemetry
 
 	// State
 	mu          sync.RWMutex
@@ -32,6 +46,12 @@ type Agent struct {
 	lastMetrics *api.HeartbeatRequest
 	lastError   error
 	lastSentAt  time.Time
+
+	// inventoryHash is the hash of the inventory the server last accepted.
+	inventoryHash string
+	// unsentEvents are the events of heartbeats that failed to send. They
+	// go out with the next heartbeat.
+	unsentEvents []api.EventLogInfo
 }
 
 var (
@@ -47,6 +67,10 @@ type AgentStatus struct {
 }
 
 func NewAgent(cfg *config.Config, logger *zap.Logger) *Agent {
+	// Host mode redirects /proc, /sys, /etc and / for every collector, so it
+	// is set up before any of them is built.
+	hostfs.Configure(cfg.Collectors.System.HostMode)
+
 	client := resty.New()
 	client.SetBaseURL(cfg.Server.APIEndpoint)
 	client.SetTimeout(time.Duration(cfg.Server.Timeout) * time.Second)
@@ -56,18 +80,44 @@ func NewAgent(cfg *config.Config, logger *zap.Logger) *Agent {
 	a := &Agent{
 		cfg:             cfg,
 		logger:          logger,
-		systemCollector: system.NewSystemCollector(cfg.Collectors.System),
+		systemCollector: system.NewSystemCollector(cfg.Collectors.System, cfg.Server),
+		attributes:      attributes.NewCollector(cfg.Host),
 		client:          client,
+		telemetry:       newTelemetry(cfg.Version()),
+	}
+
+	// Initialize Event Log Collector (Windows event log, Linux journal or syslog)
+	if runtime.GOOS == "windows" || runtime.GOOS == "linux" {
+		a.eventLogCollector = eventlog.NewCollector(cfg.Collectors.System.EventLog, cfg.Collectors.Events)
+	}
+
+	if cfg.Collectors.Cloud.Enabled {
+		a.cloudCollector = cloud.NewCollector(cfg.Collectors.Cloud)
+	}
+
+	if cfg.Collectors.Inventory.Enabled {
+		a.inventory = inventory.NewCollector(cfg.Collectors.Inventory, cfg.Collectors.System.InterfaceExclude)
 	}
 
-	// Initialize Event Log Collector (Windows only)
-	if runtime.GOOS == "windows" {
-		a.eventLogCollector = eventlog.NewCollector(cfg.Collectors.System.EventLog)
+	if cfg.Collectors.Packages.Enabled {
+		a.packages = packages.NewCollector(cfg.Collectors.Packages)
+	}
+
+	if cfg.Collectors.Updates.Enabled {
+		a.updates = updates.NewCollector(cfg.Collectors.Updates)
 	}
 
 	// Initialize Service Monitors
 	a.initServiceMonitors()
 
+	if cfg.Heartbeat.DeltaEnabled {
+		a.delta = newDeltaTracker(cfg.Heartbeat.FullSnapshotEvery)
+	}
+
+	if cfg.Heartbeat.Streams.Enabled {
+		a.initStreams()
+	}
+
 	return a
 }
 
@@ -81,7 +131,7 @@ func (a *Agent) initServiceMonitors() {
 	}
 
 	if a.cfg.Services.Systemd.Enabled {
-		if mon, err := service.NewSystemdMonitor(a.cfg.Services.Systemd.Units); err == nil {
+		if mon, err := service.NewSystemdMonitor(a.cfg.Services.Systemd.Units, a.cfg.Services.Systemd.Cgroups); err == nil {
 			a.serviceMonitors = append(a.serviceMonitors, mon)
 		} else {
 			a.logger.Warn("Failed to initialize Systemd monitor", zap.Error(err))
@@ -89,12 +139,24 @@ func (a *Agent) initServiceMonitors() {
 	}
 
 	if a.cfg.Services.Docker.Enabled {
-		if mon, err := service.NewDockerMonitor(a.cfg.Services.Docker.Containers); err == nil {
+		if mon, err := service.NewDockerMonitor(a.cfg.Services.Docker.Containers, a.cfg.Services.Docker.Cgroups); err == nil {
 			a.serviceMonitors = append(a.serviceMonitors, mon)
 		} else {
 			a.logger.Warn("Failed to initialize Docker monitor", zap.Error(err))
 		}
 	}
+
+	if a.cfg.Services.Mounts.Enabled && len(a.cfg.Services.Mounts.Mounts) > 0 {
+		a.serviceMonitors = append(a.serviceMonitors, service.NewMountMonitor(a.cfg.Services.Mounts))
+	}
+
+	if a.cfg.Services.Processes.Enabled && len(a.cfg.Services.Processes.Processes) > 0 {
+		if mon, err := service.NewProcessMonitor(a.cfg.Services.Processes); err == nil {
+			a.serviceMonitors = append(a.serviceMonitors, mon)
+		} else {
+			a.logger.Warn("Failed to initialize process monitor", zap.Error(err))
+		}
+	}
 }
 
 func (a *Agent) Run(ctx context.Context) error {
@@ -113,6 +175,10 @@ func (a *Agent) Run(ctx context.Context) error {
 		zap.String("server", a.cfg.Server.APIEndpoint),
 	)
 
+	if a.streams != nil {
+		a.streams.Start(ctx)
+	}
+
 	ticker := time.NewTicker(time.Duration(a.cfg.Collectors.IntervalSeconds) * time.Second)
 	defer ticker.Stop()
 
@@ -135,19 +201,50 @@ func (a *Agent) Run(ctx context.Context) error {
 }
 
 func (a *Agent) collectAndSend(ctx context.Context) error {
+	start := time.Now()
+	defer func() {
+		a.telemetry.RecordCycle(time.Since(start))
+	}()
+
+	request, events, err := a.collect(ctx)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	a.lastMetrics = request
+	a.mu.Unlock()
+
+	if a.streams != nil {
+		a.enqueue(request, events)
+		return nil
+	}
+
+	return a.send(ctx, request, events)
+}
+
+// collect gathers a full heartbeat from all collectors, together with the
+// event log checkpoint to commit once its events have been delivered.
+func (a *Agent) collect(ctx context.Context) (*api.HeartbeatRequest, eventlog.Checkpoint, error) {
 	a.logger.Debug("Starting collection cycle")
 
+	durations := make(map[string]time.Duration)
+
 	// Collect System Metrics
+	start := time.Now()
 	sysResult, err := a.systemCollector.Collect(ctx)
+	durations["system"] = time.Since(start)
 	if err != nil {
 		a.setError(err)
-		return fmt.Errorf("failed to collect system metrics: %w", err)
+		return nil, 0, fmt.Errorf("failed to collect system metrics: %w", err)
 	}
 
 	// Collect Service Metrics
 	var services []api.ServiceInfo
 	for _, mon := range a.serviceMonitors {
+		start := time.Now()
 		svcList, err := mon.GetServices()
+		durations["service."+mon.Name()] = time.Since(start)
 		if err != nil {
 			a.logger.Warn("Service monitor error", zap.String("monitor", mon.Name()), zap.Error(err))
 			continue
@@ -166,51 +263,235 @@ func (a *Agent) collectAndSend(ctx context.Context) error {
 		RAMTotalMB:    sysResult.System.RAMTotalMB,
 		UptimeSeconds: sysResult.System.UptimeSeconds,
 		ProcessCount:  sysResult.System.ProcessCount,
+		View: &api.CollectionView{
+			Mode:         sysResult.System.View.Mode,
+			PIDNamespace: sysResult.System.View.PIDNamespace,
+			NetNamespace: sysResult.System.View.NetNamespace,
+		},
 	}
 
 	var disks []api.DiskInfo
 	for _, d := range sysResult.Disks {
 		disks = append(disks, api.DiskInfo{
-			Name:        d.Name,
-			MountPoint:  d.MountPoint,
-			FileSystem:  d.FileSystem,
-			TotalGB:     d.TotalGB,
-			UsedGB:      d.UsedGB,
-			UsedPercent: d.UsedPercent,
+			Name:          d.Name,
+			MountPoint:    d.MountPoint,
+			FileSystem:    d.FileSystem,
+			TotalGB:       d.TotalGB,
+			UsedGB:        d.UsedGB,
+			UsedPercent:   d.UsedPercent,
+			InodesTotal:   d.InodesTotal,
+			InodesUsed:    d.InodesUsed,
+			InodesPercent: d.InodesPercent,
+			ReadOnly:      d.ReadOnly,
+			Stale:         d.Stale,
 		})
 	}
 
-	var metadata *api.AgentMetadata
-	if agentVersion != "" || agentBuild != "" {
-		metadata = &api.AgentMetadata{
-			Version:   agentVersion,
-			BuildHash: agentBuild,
-			Platform:  runtime.GOOS,
-		}
+	var diskIO []api.DiskIOInfo
+	for _, d := range sysResult.DiskIO {
+		diskIO = append(diskIO, api.DiskIOInfo{
+			Device:           d.Device,
+			MountPoints:      d.MountPoints,
+			ReadBytesPerSec:  d.ReadBytesPerSec,
+			WriteBytesPerSec: d.WriteBytesPerSec,
+			ReadIOPS:         d.ReadIOPS,
+			WriteIOPS:        d.WriteIOPS,
+			AwaitMs:          d.AwaitMs,
+			UtilPercent:      d.UtilPercent,
+			QueueDepth:       d.QueueDepth,
+			InFlight:         d.InFlight,
+		})
 	}
 
 	// Prepare Request
 	request := &api.HeartbeatRequest{
 		SystemInfo: systemInfo,
 		Disks:      disks,
+		DiskIO:     diskIO,
 		Services:   services,
 		Timestamp:  time.Now().UTC(),
-		AgentInfo:  metadata,
+	}
+
+	if cpu := sysResult.CPU; cpu != nil {
+		request.CPU = &api.CPUInfo{
+			ModelName:             cpu.ModelName,
+			PhysicalCores:         cpu.PhysicalCores,
+			LogicalCores:          cpu.LogicalCores,
+			FrequencyMHz:          cpu.FrequencyMHz,
+			UserPercent:           cpu.UserPercent,
+			SystemPercent:         cpu.SystemPercent,
+			NicePercent:           cpu.NicePercent,
+			IOWaitPercent:         cpu.IOWaitPercent,
+			IRQPercent:            cpu.IRQPercent,
+			StealPercent:          cpu.StealPercent,
+			IdlePercent:           cpu.IdlePercent,
+			PerCorePercent:        cpu.PerCorePercent,
+			Load1:                 cpu.Load1,
+			Load5:                 cpu.Load5,
+			Load15:                cpu.Load15,
+			Load1PerCore:          cpu.Load1PerCore,
+			Load5PerCore:          cpu.Load5PerCore,
+			Load15PerCore:         cpu.Load15PerCore,
+			ContextSwitchesPerSec: cpu.ContextSwitchesPerSec,
+		}
+	}
+
+	if psi := sysResult.PSI; psi != nil {
+		request.PSI = &api.PSIInfo{
+			Available: psi.Available,
+			Source:    psi.Source,
+			CPU:       toPSIResource(psi.CPU),
+			Memory:    toPSIResource(psi.Memory),
+			IO:        toPSIResource(psi.IO),
+		}
+	}
+
+	if kernel := sysResult.Kernel; kernel != nil {
+		request.Kernel = &api.KernelInfo{
+			FileHandles: toKernelLimit(kernel.FileHandles),
+			Conntrack:   toKernelLimit(kernel.Conntrack),
+			PIDs:        toKernelLimit(kernel.PIDs),
+			Threads:     toKernelLimit(kernel.Threads),
+			Processes:   kernel.Processes,
+		}
+		if s := kernel.Sockets; s != nil {
+			request.Kernel.Sockets = &api.SocketStatsInfo{
+				Used:             s.Used,
+				TCPInUse:         s.TCPInUse,
+				TCPOrphan:        s.TCPOrphan,
+				TCPTimeWait:      s.TCPTimeWait,
+				TCPAlloc:         s.TCPAlloc,
+				TCPMemPages:      s.TCPMemPages,
+				UDPInUse:         s.UDPInUse,
+				TCPOrphanPercent: s.TCPOrphanPercent,
+				TCPMemPercent:    s.TCPMemPercent,
+			}
+		}
+	}
+
+	if top := sysResult.TopProcesses; top != nil {
+		request.TopProcesses = &api.TopProcesses{
+			ByCPU:    toProcessSnapshots(top.ByCPU),
+			ByMemory: toProcessSnapshots(top.ByMemory),
+		}
+	}
+
+	if memory := sysResult.Memory; memory != nil {
+		request.Memory = &api.MemoryInfo{
+			TotalMB:            memory.TotalMB,
+			AvailableMB:        memory.AvailableMB,
+			UsedMB:             memory.UsedMB,
+			UsedPercent:        memory.UsedPercent,
+			FreeMB:             memory.FreeMB,
+			BuffersMB:          memory.BuffersMB,
+			CachedMB:           memory.CachedMB,
+			SharedMB:           memory.SharedMB,
+			SlabMB:             memory.SlabMB,
+			SwapTotalMB:        memory.SwapTotalMB,
+			SwapUsedMB:         memory.SwapUsedMB,
+			SwapUsedPercent:    memory.SwapUsedPercent,
+			SwapInBytesPerSec:  memory.SwapInBytesPerSec,
+			SwapOutBytesPerSec: memory.SwapOutBytesPerSec,
+			CommittedMB:        memory.CommittedMB,
+			CommitLimitMB:      memory.CommitLimitMB,
+			CommitPercent:      memory.CommitPercent,
+			DirtyMB:            memory.DirtyMB,
+			WritebackMB:        memory.WritebackMB,
+			HugePagesTotal:     memory.HugePagesTotal,
+			HugePagesFree:      memory.HugePagesFree,
+			HugePagesRsvd:      memory.HugePagesRsvd,
+			HugePageSizeKB:     memory.HugePageSizeKB,
+		}
+	}
+
+	start = time.Now()
+	request.HostLabels = &api.HostLabels{
+		DisplayName: a.cfg.Host.DisplayName,
+		Location:    a.cfg.Host.Location,
+		Tags:        a.cfg.Host.Tags,
+		Attributes:  a.attributes.Collect(ctx),
+	}
+	durations["attributes"] = time.Since(start)
+
+	if a.cloudCollector != nil {
+		start := time.Now()
+		request.Cloud = a.cloudCollector.Collect(ctx)
+		durations["cloud"] = time.Since(start)
+	}
+
+	// The inventory is only sent when the server does not have it yet
+	if a.inventory != nil {
+		start := time.Now()
+		inv := a.inventory.Collect(ctx)
+		durations["inventory"] = time.Since(start)
+
+		a.mu.RLock()
+		changed := inv.Hash != a.inventoryHash
+		a.mu.RUnlock()
+		if changed {
+			request.Inventory = inv
+		}
+	}
+
+	if a.packages != nil {
+		start := time.Now()
+		request.Packages = a.packages.Prepare(ctx)
+		durations["packages"] = time.Since(start)
+	}
+
+	if a.updates != nil {
+		start := time.Now()
+		request.Updates = a.updates.Collect(ctx)
+		durations["updates"] = time.Since(start)
 	}
 
 	if sysResult.Network != nil {
 		request.NetworkInfo = &api.NetworkInfo{
-			PrimaryIP: sysResult.Network.PrimaryIP,
-			PublicIP:  sysResult.Network.PublicIP,
-			InBytes:   sysResult.Network.InBytes,
-			OutBytes:  sysResult.Network.OutBytes,
+			PrimaryIP:      sysResult.Network.PrimaryIP,
+			PublicIP:       sysResult.Network.PublicIP,
+			PublicIPv6:     sysResult.Network.PublicIPv6,
+			PublicIPSource: sysResult.Network.PublicIPSource,
+			InBytes:        sysResult.Network.InBytes,
+			OutBytes:       sysResult.Network.OutBytes,
+		}
+		for _, iface := range sysResult.Network.Interfaces {
+			request.NetworkInfo.Interfaces = append(request.NetworkInfo.Interfaces, api.NetworkInterfaceInfo{
+				Name:              iface.Name,
+				MAC:               iface.MAC,
+				MTU:               iface.MTU,
+				IPv4:              iface.IPv4,
+				IPv6:              iface.IPv6,
+				Up:                iface.Up,
+				OperState:         iface.OperState,
+				SpeedMbps:         iface.SpeedMbps,
+				Duplex:            iface.Duplex,
+				IsDefault:         iface.IsDefault,
+				BytesRecv:         iface.BytesRecv,
+				BytesSent:         iface.BytesSent,
+				BytesRecvPerSec:   iface.BytesRecvPerSec,
+				BytesSentPerSec:   iface.BytesSentPerSec,
+				PacketsRecvPerSec: iface.PacketsRecvPerSec,
+				PacketsSentPerSec: iface.PacketsSentPerSec,
+				ErrorsInPerSec:    iface.ErrorsInPerSec,
+				ErrorsOutPerSec:   iface.ErrorsOutPerSec,
+				DropsInPerSec:     iface.DropsInPerSec,
+				DropsOutPerSec:    iface.DropsOutPerSec,
+			})
 		}
 	}
 
-	// Collect Event Logs (Windows only)
+	// Collect Event Logs. A failing source does not hold back the events
+	// the others returned.
+	var checkpoint eventlog.Checkpoint
 	if a.eventLogCollector != nil {
-		eventLogs, err := a.eventLogCollector.Collect()
-		if err == nil && len(eventLogs) > 0 {
+		start := time.Now()
+		eventLogs, cp, err := a.eventLogCollector.Collect()
+		durations["eventlog"] = time.Since(start)
+		checkpoint = cp
+		if err != nil {
+			a.logger.Warn("Failed to collect event logs", zap.Error(err))
+		}
+		if len(eventLogs) > 0 {
 			// Convert eventlog.EventInfo to api.EventLogInfo
 			apiEventLogs := make([]api.EventLogInfo, len(eventLogs))
 			for i, log := range eventLogs {
@@ -222,39 +503,77 @@ func (a *Agent) collectAndSend(ctx context.Context) error {
 					Message:     log.Message,
 					TimeCreated: log.TimeCreated,
 					Category:    log.Category,
+					RecordID:    log.RecordID,
+					Occurrences: log.Occurrences,
+				}
+				if log.Occurrences > 0 {
+					first := log.FirstOccurrence
+					apiEventLogs[i].FirstOccurrence = &first
 				}
 			}
 			request.EventLogs = apiEventLogs
 			a.logger.Debug("Collected event logs", zap.Int("count", len(eventLogs)))
-		} else if err != nil {
-			a.logger.Warn("Failed to collect event logs", zap.Error(err))
 		}
 	}
 
+	request.AgentInfo = a.metadata(durations)
+
+	return request, checkpoint, nil
+}
+
+// send posts the whole heartbeat in a single request. The event log
+// bookmarks advance to events once the server accepted them; the events of
+// a heartbeat that failed are kept for the next one.
+func (a *Agent) send(ctx context.Context, request *api.HeartbeatRequest, events eventlog.Checkpoint) error {
 	a.mu.Lock()
-	a.lastMetrics = request
+	if len(a.unsentEvents) > 0 {
+		merged := *request
+		merged.EventLogs = append(a.unsentEvents, request.EventLogs...)
+		request = &merged
+		a.unsentEvents = nil
+	}
 	a.mu.Unlock()
 
+	// Reduce to a delta against the last acknowledged snapshot
+	payload := request
+	var snapshot *deltaSnapshot
+	if a.delta != nil {
+		payload, snapshot = a.delta.Prepare(request)
+	}
+
 	// Log Payload for Debugging
-	payloadBytes, _ := json.MarshalIndent(request, "", "  ")
+	payloadBytes, _ := json.MarshalIndent(payload, "", "  ")
 	a.logger.Info("Sending Heartbeat Payload", zap.String("payload", string(payloadBytes)))
 
+	body, err := json.Marshal(payload)
+	if err != nil {
+		a.setError(err)
+		return fmt.Errorf("failed to encode heartbeat: %w", err)
+	}
+
 	// Send to API
+	var heartbeatResp api.HeartbeatResponse
 	resp, err := a.client.R().
 		SetContext(ctx).
 		SetHeader("X-API-Key", a.cfg.Server.APIKey).
 		SetHeader("Content-Type", "application/json").
-		SetBody(request).
+		SetBody(body).
+		SetResult(&heartbeatResp).
 		Post("/agent/heartbeat")
+	if resp != nil && resp.Request != nil {
+		a.telemetry.RecordSend("heartbeat", len(body), resp.Request.Attempt)
+	}
 
 	if err != nil {
 		a.setError(err)
+		a.keepUnsentEvents(payload.EventLogs)
 		return fmt.Errorf("failed to send heartbeat: %w", err)
 	}
 
 	if resp.IsError() {
 		err := fmt.Errorf("server returned error: %s. Body: %s", resp.Status(), resp.String())
 		a.setError(err)
+		a.keepUnsentEvents(payload.EventLogs)
 		return err
 	}
 
@@ -263,15 +582,89 @@ func (a *Agent) collectAndSend(ctx context.Context) error {
 	a.lastSentAt = time.Now()
 	a.mu.Unlock()
 
+	a.inventorySent(payload.Inventory, payload.Packages)
+	a.eventsSent(events)
+	if snapshot != nil {
+		a.delta.Commit(snapshot)
+	}
+	a.handleCommands(heartbeatResp.Commands)
+
 	a.logger.Info("Heartbeat sent successfully",
-		zap.Float64("cpu", systemInfo.CPUPercent),
-		zap.Float64("ram", systemInfo.RAMPercent),
-		zap.Int("services", len(services)),
+		zap.String("mode", payload.Mode),
+		zap.Float64("cpu", payload.SystemInfo.CPUPercent),
+		zap.Float64("ram", payload.SystemInfo.RAMPercent),
+		zap.Int("services", len(payload.Services)),
 	)
 
 	return nil
 }
 
+func (a *Agent) handleCommands(commands []api.Command) {
+	for _, cmd := range commands {
+		switch cmd.Type {
+		case api.CommandFullSnapshot:
+			a.logger.Info("Server requested a full snapshot", zap.String("command", cmd.ID))
+			if a.delta != nil {
+				a.delta.RequestFull()
+			}
+			a.mu.Lock()
+			a.inventoryHash = ""
+			a.mu.Unlock()
+			if a.packages != nil {
+				a.packages.RequestFull()
+			}
+		default:
+			a.logger.Debug("Ignoring unknown command", zap.String("command", cmd.ID), zap.String("type", cmd.Type))
+		}
+	}
+}
+
+// inventorySent records that the server accepted the host and package
+// inventory it was sent.
+func (a *Agent) inventorySent(inv *api.HostInventory, pkgs *api.PackageInventory) {
+	if inv != nil {
+		a.mu.Lock()
+		a.inventoryHash = inv.Hash
+		a.mu.Unlock()
+	}
+	if pkgs != nil && a.packages != nil {
+		a.packages.Commit(pkgs)
+	}
+}
+
+// keepUnsentEvents holds on to the events of a failed heartbeat, dropping
+// the oldest beyond the configured events queue size.
+func (a *Agent) keepUnsentEvents(events []api.EventLogInfo) {
+	if len(events) == 0 {
+		return
+	}
+	limit := a.cfg.Heartbeat.Streams.Events.QueueSize
+	if limit <= 0 {
+		limit = config.DefaultStreamsConfig().Events.QueueSize
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	kept := make([]api.EventLogInfo, 0, len(events)+len(a.unsentEvents))
+	kept = append(kept, events...)
+	a.unsentEvents = append(kept, a.unsentEvents...)
+	if over := len(a.unsentEvents) - limit; over > 0 {
+		a.unsentEvents = a.unsentEvents[over:]
+		a.logger.Warn("Dropped unsent events", zap.Int("dropped", over))
+	}
+}
+
+// eventsSent advances the persisted event log bookmarks past the events of
+// checkpoint, which the server accepted.
+func (a *Agent) eventsSent(checkpoint eventlog.Checkpoint) {
+	if a.eventLogCollector == nil {
+		return
+	}
+	if err := a.eventLogCollector.Commit(checkpoint); err != nil {
+		a.logger.Warn("Failed to save event bookmarks", zap.Error(err))
+	}
+}
+
 func (a *Agent) setError(err error) {
 	a.mu.Lock()
 	a.lastError = err
@@ -301,3 +694,47 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func toProcessSnapshots(procs []system.ProcessSnapshot) []api.ProcessSnapshot {
+	result := make([]api.ProcessSnapshot, len(procs))
+	for i, p := range procs {
+		result[i] = api.ProcessSnapshot{
+			PID:        p.PID,
+			Name:       p.Name,
+			User:       p.User,
+			Cmdline:    p.Cmdline,
+			CPUPercent: p.CPUPercent,
+			RSSBytes:   p.RSSBytes,
+		}
+	}
+	return result
+}
+
+func toPSIResource(r *system.PSIResource) *api.PSIResourceInfo {
+	if r == nil {
+		return nil
+	}
+	return &api.PSIResourceInfo{
+		Some: toPSILine(r.Some),
+		Full: toPSILine(r.Full),
+	}
+}
+
+func toPSILine(l *system.PSILine) *api.PSILineInfo {
+	if l == nil {
+		return nil
+	}
+	return &api.PSILineInfo{
+		Avg10:        l.Avg10,
+		Avg60:        l.Avg60,
+		Avg300:       l.Avg300,
+		TotalDeltaUs: l.TotalDeltaUs,
+	}
+}
+
+func toKernelLimit(l *system.KernelLimit) *api.KernelLimitInfo {
+	if l == nil {
+		return nil
+	}
+	return &api.KernelLimitInfo{Used: l.Used, Max: l.Max, Percent: l.Percent}
+}
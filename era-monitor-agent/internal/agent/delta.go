@@ -0,0 +1,247 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+)
+
+const defaultFullSnapshotEvery = 60
+
+// deltaTracker remembers the services and disks the server last acknowledged
+// so that heartbeats only need to carry the entries that changed. A full
+// snapshot is sent at startup, every fullEvery cycles, and whenever the
+// server asks for one.
+type deltaTracker struct {
+	mu        sync.Mutex
+	fullEvery int
+	cycles    int
+	forceFull bool
+
+	services map[string]serviceEntry
+	disks    map[string]string
+}
+
+type serviceEntry struct {
+	ref  api.ServiceRef
+	hash string
+}
+
+// deltaSnapshot is the state a heartbeat was built from. It only becomes the
+// tracker's baseline once the server has accepted the heartbeat.
+type deltaSnapshot struct {
+	full     bool
+	services map[string]serviceEntry
+	disks    map[string]string
+}
+
+func newDeltaTracker(fullEvery int) *deltaTracker {
+	if fullEvery <= 0 {
+		fullEvery = defaultFullSnapshotEvery
+	}
+	return &deltaTracker{
+		fullEvery: fullEvery,
+		forceFull: true,
+	}
+}
+
+// Prepare returns a copy of request reduced to a delta against the last
+// acknowledged snapshot, together with the snapshot to commit on success.
+// Entries are compared on their identity and state only; the figures that
+// change every cycle are sent as DiskUsage and ServiceMetrics for the
+// entries a delta leaves out. The snapshot hash always covers every service
+// and disk so the server can detect when it has lost sync.
+func (t *deltaTracker) Prepare(request *api.HeartbeatRequest) (*api.HeartbeatRequest, *deltaSnapshot) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	snap := &deltaSnapshot{
+		services: make(map[string]serviceEntry, len(request.Services)),
+		disks:    make(map[string]string, len(request.Disks)),
+	}
+
+	serviceHashes := make(map[string]string, len(request.Services))
+	for _, svc := range request.Services {
+		key := svc.Type + "/" + svc.Name
+		hash := serviceHash(svc)
+		snap.services[key] = serviceEntry{ref: api.ServiceRef{Name: svc.Name, Type: svc.Type}, hash: hash}
+		serviceHashes[key] = hash
+	}
+	for _, d := range request.Disks {
+		snap.disks[d.Name] = diskHash(d)
+	}
+
+	out := *request
+	out.SnapshotHash = snapshotHash(serviceHashes, snap.disks)
+
+	snap.full = t.forceFull || t.services == nil || t.cycles+1 >= t.fullEvery
+	if snap.full {
+		out.Mode = api.HeartbeatModeFull
+		return &out, snap
+	}
+
+	out.Mode = api.HeartbeatModeDelta
+	out.Services = nil
+	for _, svc := range request.Services {
+		key := svc.Type + "/" + svc.Name
+		if prev, ok := t.services[key]; !ok || prev.hash != snap.services[key].hash {
+			out.Services = append(out.Services, svc)
+		} else if m, ok := serviceMetrics(svc); ok {
+			out.ServiceMetrics = append(out.ServiceMetrics, m)
+		}
+	}
+	for key, prev := range t.services {
+		if _, ok := snap.services[key]; !ok {
+			out.RemovedServices = append(out.RemovedServices, prev.ref)
+		}
+	}
+
+	out.Disks = nil
+	for _, d := range request.Disks {
+		if prev, ok := t.disks[d.Name]; !ok || prev != snap.disks[d.Name] {
+			out.Disks = append(out.Disks, d)
+		} else {
+			out.DiskUsage = append(out.DiskUsage, diskUsage(d))
+		}
+	}
+	for name := range t.disks {
+		if _, ok := snap.disks[name]; !ok {
+			out.RemovedDisks = append(out.RemovedDisks, name)
+		}
+	}
+
+	return &out, snap
+}
+
+// Commit makes snap the new baseline after the server accepted it.
+func (t *deltaTracker) Commit(snap *deltaSnapshot) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.services = snap.services
+	t.disks = snap.disks
+	if snap.full {
+		t.cycles = 0
+		t.forceFull = false
+	} else {
+		t.cycles++
+	}
+}
+
+// RequestFull forces the next heartbeat to be a full snapshot.
+func (t *deltaTracker) RequestFull() {
+	t.mu.Lock()
+	t.forceFull = true
+	t.mu.Unlock()
+}
+
+// volatileServiceKeys are the service config entries that change from one
+// collection to the next. They are left out of the service hash so that a
+// busy process is not resent in full every cycle.
+var volatileServiceKeys = map[string]bool{
+	"cpu_percent":    true,
+	"rss_mb":         true,
+	"threads":        true,
+	"open_files":     true,
+	"uptime_seconds": true,
+	"pids":           true,
+	"free_gb":        true,
+	"used_percent":   true,
+	"status":         true, // Docker's "Up 5 minutes"
+}
+
+// serviceHash hashes the identity and state of a service, leaving out its
+// volatile config entries and cgroup usage.
+func serviceHash(svc api.ServiceInfo) string {
+	stable := svc
+	stable.Cgroup = nil
+	if svc.Config != nil {
+		stable.Config = make(map[string]interface{}, len(svc.Config))
+		for k, v := range svc.Config {
+			if !volatileServiceKeys[k] {
+				stable.Config[k] = v
+			}
+		}
+	}
+	return hashJSON(stable)
+}
+
+// diskHash hashes the identity and state of a disk, leaving out its usage.
+func diskHash(d api.DiskInfo) string {
+	stable := d
+	stable.UsedGB = 0
+	stable.UsedPercent = 0
+	stable.InodesUsed = 0
+	stable.InodesPercent = 0
+	return hashJSON(stable)
+}
+
+// serviceMetrics returns the volatile part of svc, or false if it has none.
+func serviceMetrics(svc api.ServiceInfo) (api.ServiceMetrics, bool) {
+	m := api.ServiceMetrics{Name: svc.Name, Type: svc.Type, Cgroup: svc.Cgroup}
+	for k, v := range svc.Config {
+		if volatileServiceKeys[k] {
+			if m.Metrics == nil {
+				m.Metrics = make(map[string]interface{})
+			}
+			m.Metrics[k] = v
+		}
+	}
+	return m, m.Metrics != nil || m.Cgroup != nil
+}
+
+func diskUsage(d api.DiskInfo) api.DiskUsageInfo {
+	return api.DiskUsageInfo{
+		Name:          d.Name,
+		UsedGB:        d.UsedGB,
+		UsedPercent:   d.UsedPercent,
+		InodesUsed:    d.InodesUsed,
+		InodesPercent: d.InodesPercent,
+	}
+}
+
+// usageOf returns the volatile figures of every disk and service in request.
+func usageOf(request *api.HeartbeatRequest) ([]api.DiskUsageInfo, []api.ServiceMetrics) {
+	var disks []api.DiskUsageInfo
+	for _, d := range request.Disks {
+		disks = append(disks, diskUsage(d))
+	}
+	var services []api.ServiceMetrics
+	for _, svc := range request.Services {
+		if m, ok := serviceMetrics(svc); ok {
+			services = append(services, m)
+		}
+	}
+	return disks, services
+}
+
+func hashJSON(v interface{}) string {
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func snapshotHash(services, disks map[string]string) string {
+	h := sha256.New()
+	for _, part := range []struct {
+		prefix string
+		hashes map[string]string
+	}{
+		{"service:", services},
+		{"disk:", disks},
+	} {
+		keys := make([]string, 0, len(part.hashes))
+		for k := range part.hashes {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			h.Write([]byte(part.prefix + k + "=" + part.hashes[k] + "\n"))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
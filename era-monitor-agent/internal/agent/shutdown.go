@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+	"go.uber.org/zap"
+)
+
+// NotifyShutdown tells the server that the agent is stopping on purpose so
+// that the stop is not reported as an outage. The reason is an update when
+// the updater left its marker file, a reboot or power-off when the init
+// system is shutting down, and the received signal otherwise.
+func (a *Agent) NotifyShutdown(ctx context.Context, signal string) error {
+	cfg := a.cfg.Agent.Shutdown
+	if !cfg.Notify {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	request := &api.ShutdownRequest{
+		Reason:    api.ShutdownReasonSignal,
+		Signal:    signal,
+		Timestamp: now,
+		AgentInfo: a.metadata(nil),
+	}
+
+	var downtime time.Duration
+	if cfg.UpdateMarkerPath != "" {
+		if _, err := os.Stat(cfg.UpdateMarkerPath); err == nil {
+			request.Reason = api.ShutdownReasonUpdate
+			downtime = time.Duration(cfg.UpdateDowntimeSeconds) * time.Second
+			if err := os.Remove(cfg.UpdateMarkerPath); err != nil {
+				a.logger.Warn("Failed to remove update marker", zap.Error(err))
+			}
+		}
+	}
+	if request.Reason == api.ShutdownReasonSignal {
+		switch reason := detectSystemShutdown(ctx); reason {
+		case api.ShutdownReasonReboot:
+			request.Reason = reason
+			downtime = time.Duration(cfg.RebootDowntimeSeconds) * time.Second
+		case api.ShutdownReasonPowerOff:
+			request.Reason = reason
+		}
+	}
+	if downtime > 0 {
+		expected := now.Add(downtime)
+		request.ExpectedReturn = &expected
+	}
+
+	a.logger.Info("Notifying server of shutdown",
+		zap.String("reason", request.Reason),
+		zap.String("signal", signal),
+	)
+
+	resp, err := a.client.R().
+		SetContext(ctx).
+		SetHeader("X-API-Key", a.cfg.Server.APIKey).
+		SetHeader("Content-Type", "application/json").
+		SetBody(request).
+		Post("/agent/shutdown")
+	if err != nil {
+		return fmt.Errorf("failed to send shutdown notice: %w", err)
+	}
+	if resp.IsError() {
+		return fmt.Errorf("server returned error: %s. Body: %s", resp.Status(), resp.String())
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+//go:build linux
+
+package agent
+
+import (
+	"context"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	"github.com/eracloud/era-monitor-agent/internal/api"
+)
+
+// detectSystemShutdown reports whether systemd has a reboot or power-off job
+// queued, which means the agent is being stopped as part of a system
+// shutdown.
+func detectSystemShutdown(ctx context.Context) string {
+	conn, err := dbus.NewWithContext(ctx)
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+
+	jobs, err := conn.ListJobsContext(ctx)
+	if err != nil {
+		return ""
+	}
+
+	for _, job := range jobs {
+		switch job.Unit {
+		case "reboot.target", "kexec.target", "soft-reboot.target":
+			return api.ShutdownReasonReboot
+		case "poweroff.target", "halt.target":
+			return api.ShutdownReasonPowerOff
+		}
+	}
+
+	return ""
+}
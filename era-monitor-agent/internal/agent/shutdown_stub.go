@@ -0,0 +1,9 @@
+//go:build !linux
+
+package agent
+
+import "context"
+
+func detectSystemShutdown(ctx context.Context) string {
+	return ""
+}
@@ -0,0 +1,385 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+	"github.com/eracloud/era-monitor-agent/internal/collectors/eventlog"
+	"github.com/eracloud/era-monitor-agent/internal/config"
+	"github.com/go-resty/resty/v2"
+	"go.uber.org/zap"
+)
+
+// stream uploads one kind of payload from its own bounded queue. Each stream
+// has its own HTTP client, retry policy and size cap, so a backlog on one
+// stream never holds up another.
+type stream struct {
+	name      string
+	cfg       config.StreamConfig
+	client    *resty.Client
+	apiKey    string
+	logger    *zap.Logger
+	telemetry *telemetry
+
+	// build turns a batch of queued items into a request body.
+	build func(items []interface{}) interface{}
+	// result, if set, returns a fresh value to decode the response into.
+	result func() interface{}
+	// onSent is called after the server accepted a batch.
+	onSent func(items []interface{}, result interface{})
+	// onError is called when a batch could not be sent.
+	onError func(err error)
+
+	mu      sync.Mutex
+	queue   []queuedItem
+	nextSeq uint64
+	dropped int
+	notify  chan struct{}
+}
+
+type queuedItem struct {
+	seq  uint64
+	item interface{}
+}
+
+func newStream(name string, cfg, defaults config.StreamConfig, server config.ServerConfig, logger *zap.Logger) *stream {
+	if cfg.Path == "" {
+		cfg.Path = defaults.Path
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaults.QueueSize
+	}
+	if cfg.MaxBatch <= 0 {
+		cfg.MaxBatch = defaults.MaxBatch
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = defaults.MaxBytes
+	}
+	if cfg.RetryCount < 0 {
+		cfg.RetryCount = defaults.RetryCount
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = defaults.RetryDelay
+	}
+
+	client := resty.New()
+	client.SetBaseURL(server.APIEndpoint)
+	client.SetTimeout(time.Duration(server.Timeout) * time.Second)
+	client.SetRetryCount(cfg.RetryCount)
+	client.SetRetryWaitTime(time.Duration(cfg.RetryDelay) * time.Second)
+
+	return &stream{
+		name:   name,
+		cfg:    cfg,
+		client: client,
+		apiKey: server.APIKey,
+		logger: logger.With(zap.String("stream", name)),
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// Enqueue adds items to the stream. When the queue is full the oldest items
+// are dropped so that fresh data always gets through.
+func (s *stream) Enqueue(items ...interface{}) {
+	if len(items) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	for _, item := range items {
+		s.nextSeq++
+		s.queue = append(s.queue, queuedItem{seq: s.nextSeq, item: item})
+	}
+	if over := len(s.queue) - s.cfg.QueueSize; over > 0 {
+		s.queue = s.queue[over:]
+		s.dropped += over
+		s.logger.Warn("Stream queue full, dropped oldest items", zap.Int("dropped", over))
+	}
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Len returns the number of queued items.
+func (s *stream) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queue)
+}
+
+// Dropped returns the number of items discarded because the queue was full.
+func (s *stream) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Run drains the queue until ctx is cancelled.
+func (s *stream) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.notify:
+		}
+
+		for s.Len() > 0 {
+			if err := s.flush(ctx); err != nil {
+				s.logger.Warn("Stream upload failed", zap.Error(err))
+				if s.onError != nil {
+					s.onError(err)
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(time.Duration(s.cfg.RetryDelay) * time.Second):
+				}
+			}
+		}
+	}
+}
+
+// flush sends the next batch from the head of the queue. Batches that exceed
+// MaxBytes are halved until they fit; a single oversized item is dropped
+// rather than blocking the stream.
+func (s *stream) flush(ctx context.Context) error {
+	s.mu.Lock()
+	n := len(s.queue)
+	if n > s.cfg.MaxBatch {
+		n = s.cfg.MaxBatch
+	}
+	queued := append([]queuedItem(nil), s.queue[:n]...)
+	s.mu.Unlock()
+
+	if len(queued) == 0 {
+		return nil
+	}
+
+	batch := make([]interface{}, len(queued))
+	for i, q := range queued {
+		batch[i] = q.item
+	}
+
+	var body []byte
+	for {
+		var err error
+		body, err = json.Marshal(s.build(batch))
+		if err != nil {
+			s.remove(queued[len(batch)-1].seq)
+			return fmt.Errorf("failed to encode %s batch: %w", s.name, err)
+		}
+		if len(body) <= s.cfg.MaxBytes {
+			break
+		}
+		if len(batch) == 1 {
+			s.remove(queued[0].seq)
+			return fmt.Errorf("dropped %s item of %d bytes exceeding cap of %d", s.name, len(body), s.cfg.MaxBytes)
+		}
+		batch = batch[:len(batch)/2]
+	}
+
+	req := s.client.R().
+		SetContext(ctx).
+		SetHeader("X-API-Key", s.apiKey).
+		SetHeader("Content-Type", "application/json").
+		SetBody(body)
+
+	var result interface{}
+	if s.result != nil {
+		result = s.result()
+		req.SetResult(result)
+	}
+
+	resp, err := req.Post(s.cfg.Path)
+	if resp != nil && resp.Request != nil && s.telemetry != nil {
+		s.telemetry.RecordSend(s.name, len(body), resp.Request.Attempt)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to send %s: %w", s.name, err)
+	}
+	if resp.IsError() {
+		return fmt.Errorf("server returned error: %s. Body: %s", resp.Status(), resp.String())
+	}
+
+	s.remove(queued[len(batch)-1].seq)
+	s.logger.Debug("Stream batch sent", zap.Int("items", len(batch)), zap.Int("bytes", len(body)))
+
+	if s.onSent != nil {
+		s.onSent(batch, result)
+	}
+	return nil
+}
+
+// remove drops every item up to and including seq. Enqueue may have trimmed
+// part of the batch while it was in flight, so items are matched by sequence
+// number rather than position.
+func (s *stream) remove(seq uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for n < len(s.queue) && s.queue[n].seq <= seq {
+		n++
+	}
+	s.queue = s.queue[n:]
+}
+
+// streamSet holds the metrics, inventory and event streams of a split
+// heartbeat.
+type streamSet struct {
+	metrics   *stream
+	inventory *stream
+	events    *stream
+}
+
+func (s *streamSet) all() []*stream {
+	return []*stream{s.metrics, s.inventory, s.events}
+}
+
+func (s *streamSet) Start(ctx context.Context) {
+	for _, st := range s.all() {
+		go st.Run(ctx)
+	}
+}
+
+func (a *Agent) initStreams() {
+	cfg := a.cfg.Heartbeat.Streams
+	defaults := config.DefaultStreamsConfig()
+
+	// A metrics or inventory request carries exactly one heartbeat, so these
+	// streams always send one item per batch whatever maxBatch says.
+	cfg.Metrics.MaxBatch = 1
+	cfg.Inventory.MaxBatch = 1
+
+	metrics := newStream("metrics", cfg.Metrics, defaults.Metrics, a.cfg.Server, a.logger)
+	metrics.build = func(items []interface{}) interface{} {
+		return items[0]
+	}
+	metrics.result = func() interface{} {
+		return &api.HeartbeatResponse{}
+	}
+	metrics.onSent = func(items []interface{}, result interface{}) {
+		a.mu.Lock()
+		a.lastError = nil
+		a.lastSentAt = time.Now()
+		a.mu.Unlock()
+
+		if resp, ok := result.(*api.HeartbeatResponse); ok {
+			a.handleCommands(resp.Commands)
+		}
+	}
+	metrics.onError = a.setError
+
+	inventory := newStream("inventory", cfg.Inventory, defaults.Inventory, a.cfg.Server, a.logger)
+	inventory.build = func(items []interface{}) interface{} {
+		return a.buildInventory(items[0].(*inventoryItem))
+	}
+	inventory.onSent = func(items []interface{}, result interface{}) {
+		item := items[0].(*inventoryItem)
+		if item.snapshot != nil {
+			a.delta.Commit(item.snapshot)
+		}
+		a.inventorySent(item.request.Inventory, item.request.Packages)
+	}
+
+	events := newStream("events", cfg.Events, defaults.Events, a.cfg.Server, a.logger)
+	events.build = func(items []interface{}) interface{} {
+		batch := &api.EventBatchRequest{
+			Events:    make([]api.EventLogInfo, len(items)),
+			Timestamp: time.Now().UTC(),
+		}
+		for i, item := range items {
+			batch.Events[i] = item.(eventItem).event
+		}
+		return batch
+	}
+	events.onSent = func(items []interface{}, result interface{}) {
+		var checkpoint eventlog.Checkpoint
+		for _, item := range items {
+			if cp := item.(eventItem).checkpoint; cp > checkpoint {
+				checkpoint = cp
+			}
+		}
+		a.eventsSent(checkpoint)
+	}
+
+	a.streams = &streamSet{
+		metrics:   metrics,
+		inventory: inventory,
+		events:    events,
+	}
+	for _, st := range a.streams.all() {
+		st.telemetry = a.telemetry
+	}
+}
+
+// inventoryItem is a collected heartbeat waiting on the inventory stream.
+// The delta is prepared when the item is sent rather than when it is queued,
+// so that it is computed against the latest acknowledged snapshot.
+type inventoryItem struct {
+	heartbeat *api.HeartbeatRequest
+	request   *api.InventoryRequest
+	snapshot  *deltaSnapshot
+}
+
+// buildInventory reduces item to a delta and records the request and the
+// snapshot to commit once the server accepts it.
+func (a *Agent) buildInventory(item *inventoryItem) *api.InventoryRequest {
+	inventory := item.heartbeat
+	item.snapshot = nil
+	if a.delta != nil {
+		inventory, item.snapshot = a.delta.Prepare(item.heartbeat)
+	}
+	item.request = &api.InventoryRequest{
+		Disks:           inventory.Disks,
+		Services:        inventory.Services,
+		Timestamp:       inventory.Timestamp,
+		Mode:            inventory.Mode,
+		SnapshotHash:    inventory.SnapshotHash,
+		RemovedServices: inventory.RemovedServices,
+		RemovedDisks:    inventory.RemovedDisks,
+		Inventory:       inventory.Inventory,
+		Packages:        inventory.Packages,
+	}
+	return item.request
+}
+
+// eventItem is an event waiting on the events stream. The last event of a
+// collection carries its event log checkpoint, so the bookmarks only move
+// past events once the server has them.
+type eventItem struct {
+	event      api.EventLogInfo
+	checkpoint eventlog.Checkpoint
+}
+
+// enqueue splits a collected heartbeat across the streams.
+func (a *Agent) enqueue(request *api.HeartbeatRequest, checkpoint eventlog.Checkpoint) {
+	metrics := *request
+	metrics.Disks = nil
+	metrics.Services = nil
+	metrics.EventLogs = nil
+	metrics.Inventory = nil
+	metrics.Packages = nil
+	metrics.DiskUsage, metrics.ServiceMetrics = usageOf(request)
+	a.streams.metrics.Enqueue(&metrics)
+
+	a.streams.inventory.Enqueue(&inventoryItem{heartbeat: request})
+
+	events := make([]interface{}, len(request.EventLogs))
+	for i, e := range request.EventLogs {
+		item := eventItem{event: e}
+		if i == len(events)-1 {
+			item.checkpoint = checkpoint
+		}
+		events[i] = item
+	}
+	a.streams.events.Enqueue(events...)
+}
@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+	"github.com/eracloud/era-monitor-agent/internal/collectors/cgroup"
+	"github.com/eracloud/era-monitor-agent/internal/collectors/hostfs"
+)
+
+// telemetry records how the agent itself is doing so that the server can
+// alert on sick agents instead of only on silent ones.
+type telemetry struct {
+	configVersion string
+	cgroups       *cgroup.Reader
+	containerised bool
+
+	mu           sync.Mutex
+	lastCycle    time.Duration
+	payloadBytes map[string]int
+	retries      map[string]int64
+}
+
+func newTelemetry(configVersion string) *telemetry {
+	return &telemetry{
+		configVersion: configVersion,
+		payloadBytes:  make(map[string]int),
+		retries:       make(map[string]int64),
+		cgroups:       cgroup.NewReader(),
+		containerised: hostfs.InContainer(),
+	}
+}
+
+// RecordSend records the size of a request and how many attempts it took.
+func (t *telemetry) RecordSend(name string, bytes, attempts int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.payloadBytes[name] = bytes
+	if attempts > 1 {
+		t.retries[name] += int64(attempts - 1)
+	}
+}
+
+// RecordCycle records the total duration of a collection cycle.
+func (t *telemetry) RecordCycle(d time.Duration) {
+	t.mu.Lock()
+	t.lastCycle = d
+	t.mu.Unlock()
+}
+
+// metadata builds the agent block of a heartbeat, including self-telemetry.
+func (a *Agent) metadata(durations map[string]time.Duration) *api.AgentMetadata {
+	t := a.telemetry
+	m := &api.AgentMetadata{
+		Version:       agentVersion,
+		BuildHash:     agentBuild,
+		Platform:      runtime.GOOS,
+		ConfigVersion: t.configVersion,
+		Goroutines:    runtime.NumGoroutine(),
+	}
+
+	readSelfUsage(m)
+
+	// The host's root cgroup has no limits and repeats the host figures. In
+	// a container with a private cgroup namespace the agent's own cgroup is
+	// shown as "/" as well, so the path alone does not tell them apart.
+	if cg, err := t.cgroups.ReadSelf(); err == nil &&
+		(t.containerised || cg.Path != "/" || cg.MemoryMax > 0 || cg.CPUQuotaCores > 0 || cg.PidsMax > 0) {
+		m.Cgroup = cg
+	}
+
+	if len(durations) > 0 {
+		m.CollectorDurationsMs = make(map[string]int64, len(durations))
+		for name, d := range durations {
+			m.CollectorDurationsMs[name] = d.Milliseconds()
+		}
+	}
+
+	t.mu.Lock()
+	m.LastCycleMs = t.lastCycle.Milliseconds()
+	if len(t.payloadBytes) > 0 {
+		m.PayloadBytes = make(map[string]int, len(t.payloadBytes))
+		for name, n := range t.payloadBytes {
+			m.PayloadBytes[name] = n
+		}
+	}
+	if len(t.retries) > 0 {
+		m.Retries = make(map[string]int64, len(t.retries))
+		for name, n := range t.retries {
+			m.Retries[name] = n
+		}
+	}
+	t.mu.Unlock()
+
+	if a.streams != nil {
+		m.QueueDepth = make(map[string]int)
+		m.Dropped = make(map[string]int)
+		for _, st := range a.streams.all() {
+			m.QueueDepth[st.name] = st.Len()
+			if dropped := st.Dropped(); dropped > 0 {
+				m.Dropped[st.name] = dropped
+			}
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,28 @@
+//go:build linux
+
+package agent
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+)
+
+// readSelfUsage fills in the agent's memory and open files from its own
+// /proc/self. gopsutil resolves the PID under HOST_PROC, which in host mode
+// with a private PID namespace belongs to some unrelated host process.
+func readSelfUsage(m *api.AgentMetadata) {
+	// statm is in pages; the second field is the resident set.
+	if data, err := os.ReadFile("/proc/self/statm"); err == nil {
+		if fields := strings.Fields(string(data)); len(fields) >= 2 {
+			if pages, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+				m.RSSBytes = pages * uint64(os.Getpagesize())
+			}
+		}
+	}
+	if entries, err := os.ReadDir("/proc/self/fd"); err == nil {
+		m.OpenFDs = int32(len(entries))
+	}
+}
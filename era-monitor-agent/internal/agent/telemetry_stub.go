@@ -0,0 +1,24 @@
+//go:build !linux
+
+package agent
+
+import (
+	"os"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+var self, _ = process.NewProcess(int32(os.Getpid()))
+
+func readSelfUsage(m *api.AgentMetadata) {
+	if self == nil {
+		return
+	}
+	if mem, err := self.MemoryInfo(); err == nil {
+		m.RSSBytes = mem.RSS
+	}
+	if fds, err := self.NumFDs(); err == nil {
+		m.OpenFDs = fds
+	}
+}
@@ -2,14 +2,194 @@ package api
 
 import "time"
 
+// Heartbeat modes. A full heartbeat carries every service and disk; a delta
+// heartbeat only carries the entries that changed since the last
+// acknowledged heartbeat.
+const (
+	HeartbeatModeFull  = "full"
+	HeartbeatModeDelta = "delta"
+)
+
+// Command types the server can return in a HeartbeatResponse.
+const (
+	CommandFullSnapshot = "full_snapshot"
+)
+
 type HeartbeatRequest struct {
-	SystemInfo  SystemInfo     `json:"system"`
-	Disks       []DiskInfo     `json:"disks"`
-	Services    []ServiceInfo  `json:"services"`
-	NetworkInfo *NetworkInfo   `json:"network,omitempty"`
-	EventLogs   []EventLogInfo `json:"eventLogs,omitempty"`
-	Timestamp   time.Time      `json:"timestamp"`
-	AgentInfo   *AgentMetadata `json:"agent,omitempty"`
+	SystemInfo      SystemInfo        `json:"system"`
+	CPU             *CPUInfo          `json:"cpu,omitempty"`
+	Memory          *MemoryInfo       `json:"memory,omitempty"`
+	TopProcesses    *TopProcesses     `json:"topProcesses,omitempty"`
+	PSI             *PSIInfo          `json:"psi,omitempty"`
+	Kernel          *KernelInfo       `json:"kernel,omitempty"`
+	Disks           []DiskInfo        `json:"disks"`
+	DiskIO          []DiskIOInfo      `json:"diskIo,omitempty"`
+	Services        []ServiceInfo     `json:"services"`
+	NetworkInfo     *NetworkInfo      `json:"network,omitempty"`
+	EventLogs       []EventLogInfo    `json:"eventLogs,omitempty"`
+	Timestamp       time.Time         `json:"timestamp"`
+	AgentInfo       *AgentMetadata    `json:"agent,omitempty"`
+	Mode            string            `json:"mode,omitempty"`
+	SnapshotHash    string            `json:"snapshotHash,omitempty"`
+	RemovedServices []ServiceRef      `json:"removedServices,omitempty"`
+	RemovedDisks    []string          `json:"removedDisks,omitempty"`
+	HostLabels      *HostLabels       `json:"host,omitempty"`
+	Cloud           *CloudInfo        `json:"cloud,omitempty"`
+	Inventory       *HostInventory    `json:"inventory,omitempty"`
+	Packages        *PackageInventory `json:"packages,omitempty"`
+	Updates         *UpdateStatus     `json:"updates,omitempty"`
+	DiskUsage       []DiskUsageInfo   `json:"diskUsage,omitempty"`
+	ServiceMetrics  []ServiceMetrics  `json:"serviceMetrics,omitempty"`
+}
+
+// InventoryRequest carries the service and disk inventory when the heartbeat
+// is split into separate streams.
+type InventoryRequest struct {
+	Disks           []DiskInfo        `json:"disks"`
+	Services        []ServiceInfo     `json:"services"`
+	Timestamp       time.Time         `json:"timestamp"`
+	Mode            string            `json:"mode,omitempty"`
+	SnapshotHash    string            `json:"snapshotHash,omitempty"`
+	RemovedServices []ServiceRef      `json:"removedServices,omitempty"`
+	RemovedDisks    []string          `json:"removedDisks,omitempty"`
+	Inventory       *HostInventory    `json:"inventory,omitempty"`
+	Packages        *PackageInventory `json:"packages,omitempty"`
+}
+
+// EventBatchRequest carries a batch of event log entries when the heartbeat
+// is split into separate streams.
+type EventBatchRequest struct {
+	Events    []EventLogInfo `json:"events"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+// HostLabels describes how the host is labelled in the agent configuration.
+type HostLabels struct {
+	DisplayName string            `json:"displayName,omitempty"`
+	Location    string            `json:"location,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+}
+
+// CloudInfo describes the cloud instance the host runs on, as reported by the
+// provider's instance metadata service.
+type CloudInfo struct {
+	Provider     string            `json:"provider"`
+	Region       string            `json:"region,omitempty"`
+	Zone         string            `json:"zone,omitempty"`
+	InstanceID   string            `json:"instanceId,omitempty"`
+	InstanceType string            `json:"instanceType,omitempty"`
+	InstanceName string            `json:"instanceName,omitempty"`
+	AccountID    string            `json:"accountId,omitempty"`
+	ImageID      string            `json:"imageId,omitempty"`
+	Tags         map[string]string `json:"tags,omitempty"`
+}
+
+// HostInventory is the hardware and OS inventory of the host. It is sent at
+// startup and whenever Hash changes, so the server keeps the last one it got.
+type HostInventory struct {
+	Hash string `json:"hash"`
+
+	OS              string    `json:"os"`
+	Platform        string    `json:"platform,omitempty"`
+	PlatformVersion string    `json:"platformVersion,omitempty"`
+	KernelVersion   string    `json:"kernelVersion,omitempty"`
+	Arch            string    `json:"arch"`
+	BootTime        time.Time `json:"bootTime"`
+
+	// Virtualization is the hypervisor or container system, e.g. "kvm" or
+	// "docker", with Role "guest" or "host".
+	Virtualization     string `json:"virtualization,omitempty"`
+	VirtualizationRole string `json:"virtualizationRole,omitempty"`
+	Container          string `json:"container,omitempty"`
+
+	DMI *DMIInfo `json:"dmi,omitempty"`
+
+	CPUModel      string `json:"cpuModel,omitempty"`
+	CPUSockets    int    `json:"cpuSockets,omitempty"`
+	CPUCores      int    `json:"cpuCores"`
+	CPUThreads    int    `json:"cpuThreads"`
+	MemoryTotalMB uint64 `json:"memoryTotalMb"`
+
+	BlockDevices []BlockDeviceInfo `json:"blockDevices,omitempty"`
+	NICs         []NICInfo         `json:"nics,omitempty"`
+}
+
+// DMIInfo is the system firmware identification from SMBIOS.
+type DMIInfo struct {
+	SystemVendor  string `json:"systemVendor,omitempty"`
+	ProductName   string `json:"productName,omitempty"`
+	ProductSerial string `json:"productSerial,omitempty"`
+	ProductUUID   string `json:"productUuid,omitempty"`
+	BoardVendor   string `json:"boardVendor,omitempty"`
+	BoardName     string `json:"boardName,omitempty"`
+	BIOSVendor    string `json:"biosVendor,omitempty"`
+	BIOSVersion   string `json:"biosVersion,omitempty"`
+	BIOSDate      string `json:"biosDate,omitempty"`
+}
+
+// BlockDeviceInfo describes a physical or virtual disk.
+type BlockDeviceInfo struct {
+	Name       string `json:"name"`
+	SizeBytes  uint64 `json:"sizeBytes"`
+	Model      string `json:"model,omitempty"`
+	Vendor     string `json:"vendor,omitempty"`
+	Serial     string `json:"serial,omitempty"`
+	Rotational bool   `json:"rotational"`
+	Removable  bool   `json:"removable,omitempty"`
+}
+
+// NICInfo describes a network adapter. Virtual is set for interfaces without
+// a backing device, such as bridges and veth pairs.
+type NICInfo struct {
+	Name      string `json:"name"`
+	MAC       string `json:"mac,omitempty"`
+	Driver    string `json:"driver,omitempty"`
+	SpeedMbps int    `json:"speedMbps,omitempty"`
+	Virtual   bool   `json:"virtual,omitempty"`
+}
+
+// PackageInventory lists installed software packages. In full mode Packages
+// is the complete list; in delta mode it holds the packages installed or
+// upgraded since the last accepted report and Removed those uninstalled.
+// rpm packages are identified by version too, since installonly packages
+// like the kernel have several versions installed at once; an rpm upgrade
+// therefore lists the old version in Removed. Hash and Count always describe
+// the complete list.
+type PackageInventory struct {
+	Mode     string        `json:"mode"`
+	Hash     string        `json:"hash"`
+	Count    int           `json:"count"`
+	Packages []PackageInfo `json:"packages,omitempty"`
+	Removed  []PackageInfo `json:"removed,omitempty"`
+}
+
+// PackageInfo is one installed package. Manager is "dpkg" or "rpm".
+type PackageInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Arch    string `json:"arch,omitempty"`
+	Manager string `json:"manager"`
+}
+
+// UpdateStatus summarises pending OS updates and whether the host needs a
+// reboot. Status is "reboot-required", "security-updates",
+// "updates-available" or "ok". The counts come from the package manager's
+// cache, which the agent does not refresh; MetadataUpdatedAt tells how old
+// it is.
+type UpdateStatus struct {
+	Status            string     `json:"status"`
+	PackageManager    string     `json:"packageManager"`
+	PendingUpdates    int        `json:"pendingUpdates"`
+	SecurityUpdates   int        `json:"securityUpdates"`
+	RebootRequired    bool       `json:"rebootRequired"`
+	RebootReasons     []string   `json:"rebootReasons,omitempty"`
+	RebootPackages    []string   `json:"rebootPackages,omitempty"`
+	RunningKernel     string     `json:"runningKernel,omitempty"`
+	LatestKernel      string     `json:"latestKernel,omitempty"`
+	MetadataUpdatedAt *time.Time `json:"metadataUpdatedAt,omitempty"`
+	CheckedAt         time.Time  `json:"checkedAt"`
+	Error             string     `json:"error,omitempty"`
 }
 
 type SystemInfo struct {
@@ -22,6 +202,76 @@ type SystemInfo struct {
 	RAMTotalMB    uint64  `json:"ramTotalMb"`
 	UptimeSeconds uint64  `json:"uptimeSeconds"`
 	ProcessCount  int     `json:"processCount"`
+
+	View *CollectionView `json:"view,omitempty"`
+}
+
+// CollectionView tells which view of the system the metrics reflect. Mode is
+// "host" for an agent on the host, "container" for one that only sees its
+// container and "host-mounts" for one that reads the host through mounted
+// host paths. The namespace fields are "host", "container" or "unknown".
+type CollectionView struct {
+	Mode         string `json:"mode"`
+	PIDNamespace string `json:"pidNamespace"`
+	NetNamespace string `json:"netNamespace"`
+}
+
+// CPUInfo breaks CPU usage down by state and by core. Load averages are also
+// given divided by the number of logical cores, so 1.0 means fully loaded.
+type CPUInfo struct {
+	ModelName     string  `json:"modelName,omitempty"`
+	PhysicalCores int     `json:"physicalCores"`
+	LogicalCores  int     `json:"logicalCores"`
+	FrequencyMHz  float64 `json:"frequencyMhz,omitempty"`
+
+	UserPercent    float64   `json:"userPercent"`
+	SystemPercent  float64   `json:"systemPercent"`
+	NicePercent    float64   `json:"nicePercent"`
+	IOWaitPercent  float64   `json:"iowaitPercent"`
+	IRQPercent     float64   `json:"irqPercent"`
+	StealPercent   float64   `json:"stealPercent"`
+	IdlePercent    float64   `json:"idlePercent"`
+	PerCorePercent []float64 `json:"perCorePercent,omitempty"`
+
+	Load1         float64 `json:"load1"`
+	Load5         float64 `json:"load5"`
+	Load15        float64 `json:"load15"`
+	Load1PerCore  float64 `json:"load1PerCore"`
+	Load5PerCore  float64 `json:"load5PerCore"`
+	Load15PerCore float64 `json:"load15PerCore"`
+
+	ContextSwitchesPerSec float64 `json:"contextSwitchesPerSec"`
+}
+
+// MemoryInfo describes memory pressure. Sizes are in MB and swap rates in
+// bytes per second.
+type MemoryInfo struct {
+	TotalMB     uint64  `json:"totalMb"`
+	AvailableMB uint64  `json:"availableMb"`
+	UsedMB      uint64  `json:"usedMb"`
+	UsedPercent float64 `json:"usedPercent"`
+	FreeMB      uint64  `json:"freeMb"`
+	BuffersMB   uint64  `json:"buffersMb"`
+	CachedMB    uint64  `json:"cachedMb"`
+	SharedMB    uint64  `json:"sharedMb"`
+	SlabMB      uint64  `json:"slabMb"`
+
+	SwapTotalMB        uint64  `json:"swapTotalMb"`
+	SwapUsedMB         uint64  `json:"swapUsedMb"`
+	SwapUsedPercent    float64 `json:"swapUsedPercent"`
+	SwapInBytesPerSec  float64 `json:"swapInBytesPerSec"`
+	SwapOutBytesPerSec float64 `json:"swapOutBytesPerSec"`
+
+	CommittedMB   uint64  `json:"committedMb"`
+	CommitLimitMB uint64  `json:"commitLimitMb"`
+	CommitPercent float64 `json:"commitPercent"`
+	DirtyMB       uint64  `json:"dirtyMb"`
+	WritebackMB   uint64  `json:"writebackMb"`
+
+	HugePagesTotal uint64 `json:"hugePagesTotal"`
+	HugePagesFree  uint64 `json:"hugePagesFree"`
+	HugePagesRsvd  uint64 `json:"hugePagesRsvd"`
+	HugePageSizeKB uint64 `json:"hugePageSizeKb,omitempty"`
 }
 
 type DiskInfo struct {
@@ -31,6 +281,96 @@ type DiskInfo struct {
 	TotalGB     float64 `json:"totalGb"`
 	UsedGB      float64 `json:"usedGb"`
 	UsedPercent float64 `json:"usedPercent"`
+
+	InodesTotal   uint64  `json:"inodesTotal,omitempty"`
+	InodesUsed    uint64  `json:"inodesUsed,omitempty"`
+	InodesPercent float64 `json:"inodesPercent,omitempty"`
+	ReadOnly      bool    `json:"readOnly,omitempty"`
+	Stale         bool    `json:"stale,omitempty"`
+}
+
+// PSIInfo holds Linux Pressure Stall Information, read system-wide ("proc")
+// or from the agent's cgroup inside a container ("cgroup").
+type PSIInfo struct {
+	Available bool             `json:"available"`
+	Source    string           `json:"source,omitempty"`
+	CPU       *PSIResourceInfo `json:"cpu,omitempty"`
+	Memory    *PSIResourceInfo `json:"memory,omitempty"`
+	IO        *PSIResourceInfo `json:"io,omitempty"`
+}
+
+type PSIResourceInfo struct {
+	Some *PSILineInfo `json:"some,omitempty"`
+	Full *PSILineInfo `json:"full,omitempty"`
+}
+
+// PSILineInfo holds stall percentages averaged over 10, 60 and 300 seconds
+// and the stall time in microseconds since the previous heartbeat.
+type PSILineInfo struct {
+	Avg10        float64 `json:"avg10"`
+	Avg60        float64 `json:"avg60"`
+	Avg300       float64 `json:"avg300"`
+	TotalDeltaUs uint64  `json:"totalDeltaUs"`
+}
+
+// KernelInfo reports kernel tables that can run out independently of CPU
+// and memory. PIDs and Threads both count every task on the system.
+type KernelInfo struct {
+	FileHandles *KernelLimitInfo `json:"fileHandles,omitempty"`
+	Conntrack   *KernelLimitInfo `json:"conntrack,omitempty"`
+	PIDs        *KernelLimitInfo `json:"pids,omitempty"`
+	Threads     *KernelLimitInfo `json:"threads,omitempty"`
+	Processes   int              `json:"processes"`
+	Sockets     *SocketStatsInfo `json:"sockets,omitempty"`
+}
+
+type KernelLimitInfo struct {
+	Used    uint64  `json:"used"`
+	Max     uint64  `json:"max"`
+	Percent float64 `json:"percent"`
+}
+
+type SocketStatsInfo struct {
+	Used             uint64  `json:"used"`
+	TCPInUse         uint64  `json:"tcpInUse"`
+	TCPOrphan        uint64  `json:"tcpOrphan"`
+	TCPTimeWait      uint64  `json:"tcpTimeWait"`
+	TCPAlloc         uint64  `json:"tcpAlloc"`
+	TCPMemPages      uint64  `json:"tcpMemPages"`
+	UDPInUse         uint64  `json:"udpInUse"`
+	TCPOrphanPercent float64 `json:"tcpOrphanPercent"`
+	TCPMemPercent    float64 `json:"tcpMemPercent"`
+}
+
+// TopProcesses lists the heaviest processes by CPU and by memory, so that an
+// alert can show which process caused it.
+type TopProcesses struct {
+	ByCPU    []ProcessSnapshot `json:"byCpu,omitempty"`
+	ByMemory []ProcessSnapshot `json:"byMemory,omitempty"`
+}
+
+type ProcessSnapshot struct {
+	PID        int32   `json:"pid"`
+	Name       string  `json:"name"`
+	User       string  `json:"user,omitempty"`
+	Cmdline    string  `json:"cmdline,omitempty"`
+	CPUPercent float64 `json:"cpuPercent"`
+	RSSBytes   uint64  `json:"rssBytes"`
+}
+
+// DiskIOInfo describes the load on one block device over the last collection
+// interval.
+type DiskIOInfo struct {
+	Device           string   `json:"device"`
+	MountPoints      []string `json:"mountPoints,omitempty"`
+	ReadBytesPerSec  float64  `json:"readBytesPerSec"`
+	WriteBytesPerSec float64  `json:"writeBytesPerSec"`
+	ReadIOPS         float64  `json:"readIops"`
+	WriteIOPS        float64  `json:"writeIops"`
+	AwaitMs          float64  `json:"awaitMs"`
+	UtilPercent      float64  `json:"utilPercent"`
+	QueueDepth       float64  `json:"queueDepth"`
+	InFlight         uint64   `json:"inFlight"`
 }
 
 type ServiceInfo struct {
@@ -39,19 +379,136 @@ type ServiceInfo struct {
 	Type        string                 `json:"type"`
 	Status      string                 `json:"status"`
 	Config      map[string]interface{} `json:"config,omitempty"`
+	Cgroup      *CgroupInfo            `json:"cgroup,omitempty"`
+}
+
+// CgroupInfo reports the resource limits and usage of a cgroup. Limits are
+// zero when unlimited. Percentages cover the time since the previous
+// heartbeat; counters are cumulative.
+type CgroupInfo struct {
+	Version int    `json:"version"`
+	Path    string `json:"path"`
+
+	MemoryCurrent uint64  `json:"memoryCurrent"`
+	MemoryMax     uint64  `json:"memoryMax,omitempty"`
+	MemoryPercent float64 `json:"memoryPercent,omitempty"`
+
+	CPUQuotaCores    float64 `json:"cpuQuotaCores,omitempty"`
+	CPUPercent       float64 `json:"cpuPercent"`
+	NrPeriods        uint64  `json:"nrPeriods"`
+	NrThrottled      uint64  `json:"nrThrottled"`
+	ThrottledUsec    uint64  `json:"throttledUsec"`
+	ThrottledPercent float64 `json:"throttledPercent"`
+
+	PidsCurrent uint64 `json:"pidsCurrent"`
+	PidsMax     uint64 `json:"pidsMax,omitempty"`
+
+	IOReadBytes  uint64 `json:"ioReadBytes"`
+	IOWriteBytes uint64 `json:"ioWriteBytes"`
+	IOReadOps    uint64 `json:"ioReadOps"`
+	IOWriteOps   uint64 `json:"ioWriteOps"`
+}
+
+// DiskUsageInfo carries the usage figures of a disk, which change every
+// cycle. They travel with the metrics whenever the disk's full entry is not
+// part of the payload.
+type DiskUsageInfo struct {
+	Name          string  `json:"name"`
+	UsedGB        float64 `json:"usedGb"`
+	UsedPercent   float64 `json:"usedPercent"`
+	InodesUsed    uint64  `json:"inodesUsed,omitempty"`
+	InodesPercent float64 `json:"inodesPercent,omitempty"`
+}
+
+// ServiceMetrics carries the figures of a service that change every cycle,
+// such as CPU usage or uptime, whenever its full entry is not part of the
+// payload.
+type ServiceMetrics struct {
+	Name    string                 `json:"name"`
+	Type    string                 `json:"type"`
+	Metrics map[string]interface{} `json:"metrics,omitempty"`
+	Cgroup  *CgroupInfo            `json:"cgroup,omitempty"`
+}
+
+// ServiceRef identifies a service that disappeared since the last heartbeat.
+type ServiceRef struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
 }
 
 type NetworkInfo struct {
-	PrimaryIP string `json:"primaryIp"`
-	PublicIP  string `json:"publicIp,omitempty"`
-	InBytes   uint64 `json:"inBytes"`
-	OutBytes  uint64 `json:"outBytes"`
+	PrimaryIP      string `json:"primaryIp"`
+	PublicIP       string `json:"publicIp,omitempty"`
+	PublicIPv6     string `json:"publicIpv6,omitempty"`
+	PublicIPSource string `json:"publicIpSource,omitempty"`
+	InBytes        uint64 `json:"inBytes"`
+	OutBytes       uint64 `json:"outBytes"`
+
+	Interfaces []NetworkInterfaceInfo `json:"interfaces,omitempty"`
+}
+
+type NetworkInterfaceInfo struct {
+	Name      string   `json:"name"`
+	MAC       string   `json:"mac,omitempty"`
+	MTU       int      `json:"mtu"`
+	IPv4      []string `json:"ipv4,omitempty"`
+	IPv6      []string `json:"ipv6,omitempty"`
+	Up        bool     `json:"up"`
+	OperState string   `json:"operState,omitempty"`
+	SpeedMbps int      `json:"speedMbps,omitempty"`
+	Duplex    string   `json:"duplex,omitempty"`
+	IsDefault bool     `json:"isDefault"`
+
+	BytesRecv uint64 `json:"bytesRecv"`
+	BytesSent uint64 `json:"bytesSent"`
+
+	BytesRecvPerSec   float64 `json:"bytesRecvPerSec"`
+	BytesSentPerSec   float64 `json:"bytesSentPerSec"`
+	PacketsRecvPerSec float64 `json:"packetsRecvPerSec"`
+	PacketsSentPerSec float64 `json:"packetsSentPerSec"`
+	ErrorsInPerSec    float64 `json:"errorsInPerSec"`
+	ErrorsOutPerSec   float64 `json:"errorsOutPerSec"`
+	DropsInPerSec     float64 `json:"dropsInPerSec"`
+	DropsOutPerSec    float64 `json:"dropsOutPerSec"`
 }
 
 type AgentMetadata struct {
 	Version   string `json:"version"`
 	BuildHash string `json:"buildHash"`
 	Platform  string `json:"platform"`
+
+	// Self-telemetry. Durations, payload sizes and retries describe the
+	// previous collection cycle; queue depths are sampled at collection time.
+	ConfigVersion        string           `json:"configVersion,omitempty"`
+	RSSBytes             uint64           `json:"rssBytes,omitempty"`
+	Goroutines           int              `json:"goroutines,omitempty"`
+	OpenFDs              int32            `json:"openFds,omitempty"`
+	CollectorDurationsMs map[string]int64 `json:"collectorDurationsMs,omitempty"`
+	LastCycleMs          int64            `json:"lastCycleMs,omitempty"`
+	PayloadBytes         map[string]int   `json:"payloadBytes,omitempty"`
+	QueueDepth           map[string]int   `json:"queueDepth,omitempty"`
+	Retries              map[string]int64 `json:"retries,omitempty"`
+	Dropped              map[string]int   `json:"dropped,omitempty"`
+
+	// Cgroup is the agent's own systemd unit or container.
+	Cgroup *CgroupInfo `json:"cgroup,omitempty"`
+}
+
+// Reasons an agent gives for stopping.
+const (
+	ShutdownReasonSignal   = "signal"
+	ShutdownReasonReboot   = "reboot"
+	ShutdownReasonPowerOff = "poweroff"
+	ShutdownReasonUpdate   = "update"
+)
+
+// ShutdownRequest is the last message an agent sends before a planned stop.
+type ShutdownRequest struct {
+	Reason         string         `json:"reason"`
+	Signal         string         `json:"signal,omitempty"`
+	ExpectedReturn *time.Time     `json:"expectedReturn,omitempty"`
+	Timestamp      time.Time      `json:"timestamp"`
+	AgentInfo      *AgentMetadata `json:"agent,omitempty"`
 }
 
 type HeartbeatResponse struct {
@@ -76,4 +533,12 @@ type EventLogInfo struct {
 	Message     string    `json:"message"`
 	TimeCreated time.Time `json:"timeCreated"`
 	Category    string    `json:"category"`
+
+	// RecordID is the event's sequence number in its log, where it has one.
+	RecordID uint64 `json:"recordId,omitempty"`
+
+	// Occurrences is set on the summary of a flood of identical events,
+	// which spans FirstOccurrence to TimeCreated.
+	Occurrences     int        `json:"occurrences,omitempty"`
+	FirstOccurrence *time.Time `json:"firstOccurrence,omitempty"`
 }
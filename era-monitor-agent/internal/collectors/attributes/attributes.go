@@ -0,0 +1,128 @@
+package attributes
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eracloud/era-monitor-agent/internal/config"
+)
+
+const commandTimeout = 10 * time.Second
+
+// Collector gathers host attributes from the static configuration and from
+// dynamic sources such as a facts directory or command output, so that
+// configuration management can label hosts automatically.
+type Collector struct {
+	config  config.HostConfig
+	refresh time.Duration
+
+	mu        sync.Mutex
+	dynamic   map[string]string
+	refreshed time.Time
+}
+
+// NewCollector creates a new attribute collector
+func NewCollector(cfg config.HostConfig) *Collector {
+	refresh := time.Duration(cfg.AttributesRefreshSeconds) * time.Second
+	if refresh <= 0 {
+		refresh = 5 * time.Minute
+	}
+	return &Collector{
+		config:  cfg,
+		refresh: refresh,
+	}
+}
+
+// Collect returns the merged attributes. Dynamic sources are re-read at most
+// once per refresh interval; static attributes from the configuration always
+// win over dynamic ones with the same key.
+func (c *Collector) Collect(ctx context.Context) map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.dynamic == nil || time.Since(c.refreshed) >= c.refresh {
+		c.dynamic = c.collectDynamic(ctx)
+		c.refreshed = time.Now()
+	}
+
+	result := make(map[string]string, len(c.dynamic)+len(c.config.Attributes))
+	for k, v := range c.dynamic {
+		result[k] = v
+	}
+	for k, v := range c.config.Attributes {
+		result[k] = v
+	}
+	return result
+}
+
+func (c *Collector) collectDynamic(ctx context.Context) map[string]string {
+	result := make(map[string]string)
+
+	if c.config.AttributesDir != "" {
+		entries, err := os.ReadDir(c.config.AttributesDir)
+		if err == nil {
+			for _, entry := range entries {
+				if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+					continue
+				}
+				data, err := os.ReadFile(filepath.Join(c.config.AttributesDir, entry.Name()))
+				if err != nil {
+					continue
+				}
+				parseInto(result, data)
+			}
+		}
+	}
+
+	for _, command := range c.config.AttributeCommands {
+		output, err := runCommand(ctx, command)
+		if err != nil {
+			continue // Skip failing commands, keep the other sources
+		}
+		parseInto(result, output)
+	}
+
+	return result
+}
+
+func runCommand(ctx context.Context, command string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	return cmd.Output()
+}
+
+// parseInto reads key=value lines into attrs. Blank lines and lines starting
+// with '#' are ignored.
+func parseInto(attrs map[string]string, data []byte) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		attrs[key] = strings.TrimSpace(value)
+	}
+}
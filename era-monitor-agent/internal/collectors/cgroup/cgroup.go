@@ -0,0 +1,55 @@
+// Package cgroup reads resource limits and usage of cgroups, for systemd
+// units, containers and the agent itself. Both the v1 and the unified v2
+// hierarchy are supported.
+package cgroup
+
+import (
+	"sync"
+	"time"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+)
+
+// Reader reads cgroups and remembers the previous CPU counters of each one
+// to compute usage and throttling over the interval between reads.
+type Reader struct {
+	mu   sync.Mutex
+	last map[string]cpuSample
+}
+
+type cpuSample struct {
+	at        time.Time
+	usageUsec uint64
+	periods   uint64
+	throttled uint64
+}
+
+func NewReader() *Reader {
+	return &Reader{last: make(map[string]cpuSample)}
+}
+
+// applyDeltas fills in the interval based percentages of info.
+func (r *Reader) applyDeltas(info *api.CgroupInfo, usageUsec uint64) {
+	now := time.Now()
+
+	r.mu.Lock()
+	prev, ok := r.last[info.Path]
+	r.last[info.Path] = cpuSample{at: now, usageUsec: usageUsec, periods: info.NrPeriods, throttled: info.NrThrottled}
+	r.mu.Unlock()
+
+	if !ok {
+		return
+	}
+	if elapsed := now.Sub(prev.at).Microseconds(); elapsed > 0 && usageUsec >= prev.usageUsec {
+		info.CPUPercent = float64(usageUsec-prev.usageUsec) / float64(elapsed) * 100
+	}
+	if info.NrPeriods > prev.periods && info.NrThrottled >= prev.throttled {
+		info.ThrottledPercent = float64(info.NrThrottled-prev.throttled) / float64(info.NrPeriods-prev.periods) * 100
+	}
+}
+
+func finish(info *api.CgroupInfo) {
+	if info.MemoryMax > 0 {
+		info.MemoryPercent = float64(info.MemoryCurrent) / float64(info.MemoryMax) * 100
+	}
+}
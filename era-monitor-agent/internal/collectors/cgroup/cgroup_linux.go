@@ -0,0 +1,273 @@
+//go:build linux
+
+package cgroup
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+	"github.com/eracloud/era-monitor-agent/internal/collectors/hostfs"
+)
+
+// v1Unlimited is the smallest value v1 memory controllers use for "no limit";
+// the exact figure depends on the page size.
+const v1Unlimited = 1 << 62
+
+// selfRoot is the cgroup hierarchy as the agent itself sees it. The paths in
+// /proc/self/cgroup are relative to the agent's cgroup namespace, so its own
+// cgroup is always looked up here, even in host mode: under a private cgroup
+// namespace the agent's cgroup is "/", which below the host mount would be
+// the host's root cgroup.
+const selfRoot = "/sys/fs/cgroup"
+
+// mountRoot is the host's cgroup hierarchy, used for units and containers.
+func mountRoot() string {
+	return hostfs.Sys("fs/cgroup")
+}
+
+func unified(root string) bool {
+	_, err := os.Stat(filepath.Join(root, "cgroup.controllers"))
+	return err == nil
+}
+
+// Read returns the limits and usage of the cgroup at path, relative to the
+// hierarchy root, e.g. "system.slice/nginx.service".
+func (r *Reader) Read(path string) (*api.CgroupInfo, error) {
+	return r.read(mountRoot(), path, func(string) string { return path })
+}
+
+// ReadSelf returns the limits and usage of the agent's own cgroup. Inside a
+// container with a cgroup namespace this is the container.
+func (r *Reader) ReadSelf() (*api.CgroupInfo, error) {
+	paths := selfPaths()
+	if len(paths) == 0 {
+		return nil, errors.New("cgroup of the agent not found")
+	}
+	// On hybrid hosts the unified line exists but controllers are on v1.
+	display := paths[""]
+	if !unified(selfRoot) {
+		display = paths["memory"]
+	}
+	if display == "" {
+		return nil, errors.New("cgroup of the agent not found")
+	}
+	return r.read(selfRoot, display, func(controller string) string {
+		if p, ok := paths[controller]; ok {
+			return p
+		}
+		return display
+	})
+}
+
+// Exists reports whether a cgroup exists at path.
+func Exists(path string) bool {
+	root := mountRoot()
+	dir := filepath.Join(root, path)
+	if !unified(root) {
+		dir = filepath.Join(root, "memory", path)
+	}
+	info, err := os.Stat(dir)
+	return err == nil && info.IsDir()
+}
+
+// ContainerPath returns the cgroup of a Docker container, for both the
+// systemd and the cgroupfs cgroup driver, or "" when none is found.
+func ContainerPath(id string) string {
+	for _, candidate := range []string{
+		"system.slice/docker-" + id + ".scope",
+		"docker/" + id,
+	} {
+		if Exists(candidate) {
+			return candidate
+		}
+	}
+	return ""
+}
+
+func (r *Reader) read(root, display string, pathFor func(controller string) string) (*api.CgroupInfo, error) {
+	var info *api.CgroupInfo
+	var usageUsec uint64
+	var err error
+	if unified(root) {
+		info, usageUsec, err = readV2(filepath.Join(root, pathFor("")))
+	} else {
+		info, usageUsec, err = readV1(func(controller string) string {
+			return filepath.Join(root, controller, pathFor(controller))
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info.Path = display
+	finish(info)
+	r.applyDeltas(info, usageUsec)
+	return info, nil
+}
+
+func readV2(dir string) (*api.CgroupInfo, uint64, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, 0, err
+	}
+
+	info := &api.CgroupInfo{Version: 2}
+	info.MemoryCurrent, _ = readUint(filepath.Join(dir, "memory.current"))
+	info.MemoryMax, _ = readUint(filepath.Join(dir, "memory.max"))
+	info.PidsCurrent, _ = readUint(filepath.Join(dir, "pids.current"))
+	info.PidsMax, _ = readUint(filepath.Join(dir, "pids.max"))
+
+	// cpu.max is "$MAX $PERIOD", with "max" for no limit.
+	if fields := strings.Fields(readString(filepath.Join(dir, "cpu.max"))); len(fields) == 2 {
+		quota, err1 := strconv.ParseFloat(fields[0], 64)
+		period, err2 := strconv.ParseFloat(fields[1], 64)
+		if err1 == nil && err2 == nil && period > 0 {
+			info.CPUQuotaCores = quota / period
+		}
+	}
+
+	stat := readKeyValues(filepath.Join(dir, "cpu.stat"))
+	info.NrPeriods = stat["nr_periods"]
+	info.NrThrottled = stat["nr_throttled"]
+	info.ThrottledUsec = stat["throttled_usec"]
+
+	// io.stat has one line per device: "8:0 rbytes=1 wbytes=2 rios=3 wios=4 ...".
+	for _, line := range readLines(filepath.Join(dir, "io.stat")) {
+		for _, field := range strings.Fields(line)[1:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			n, _ := strconv.ParseUint(value, 10, 64)
+			switch key {
+			case "rbytes":
+				info.IOReadBytes += n
+			case "wbytes":
+				info.IOWriteBytes += n
+			case "rios":
+				info.IOReadOps += n
+			case "wios":
+				info.IOWriteOps += n
+			}
+		}
+	}
+
+	return info, stat["usage_usec"], nil
+}
+
+func readV1(dir func(controller string) string) (*api.CgroupInfo, uint64, error) {
+	if _, err := os.Stat(dir("memory")); err != nil {
+		return nil, 0, err
+	}
+
+	info := &api.CgroupInfo{Version: 1}
+	info.MemoryCurrent, _ = readUint(filepath.Join(dir("memory"), "memory.usage_in_bytes"))
+	if limit, ok := readUint(filepath.Join(dir("memory"), "memory.limit_in_bytes")); ok && limit < v1Unlimited {
+		info.MemoryMax = limit
+	}
+	info.PidsCurrent, _ = readUint(filepath.Join(dir("pids"), "pids.current"))
+	info.PidsMax, _ = readUint(filepath.Join(dir("pids"), "pids.max"))
+
+	quota, err := strconv.ParseInt(readString(filepath.Join(dir("cpu"), "cpu.cfs_quota_us")), 10, 64)
+	if period, ok := readUint(filepath.Join(dir("cpu"), "cpu.cfs_period_us")); err == nil && ok && quota > 0 && period > 0 {
+		info.CPUQuotaCores = float64(quota) / float64(period)
+	}
+
+	stat := readKeyValues(filepath.Join(dir("cpu"), "cpu.stat"))
+	info.NrPeriods = stat["nr_periods"]
+	info.NrThrottled = stat["nr_throttled"]
+	info.ThrottledUsec = stat["throttled_time"] / 1000
+
+	// blkio files have lines such as "8:0 Read 1234" and a "Total" line.
+	for _, line := range readLines(filepath.Join(dir("blkio"), "blkio.throttle.io_service_bytes")) {
+		if fields := strings.Fields(line); len(fields) == 3 {
+			n, _ := strconv.ParseUint(fields[2], 10, 64)
+			switch fields[1] {
+			case "Read":
+				info.IOReadBytes += n
+			case "Write":
+				info.IOWriteBytes += n
+			}
+		}
+	}
+	for _, line := range readLines(filepath.Join(dir("blkio"), "blkio.throttle.io_serviced")) {
+		if fields := strings.Fields(line); len(fields) == 3 {
+			n, _ := strconv.ParseUint(fields[2], 10, 64)
+			switch fields[1] {
+			case "Read":
+				info.IOReadOps += n
+			case "Write":
+				info.IOWriteOps += n
+			}
+		}
+	}
+
+	usageNs, _ := readUint(filepath.Join(dir("cpuacct"), "cpuacct.usage"))
+	return info, usageNs / 1000, nil
+}
+
+// selfPaths returns the agent's cgroup per v1 controller, and under the key
+// "" the path in the unified hierarchy.
+func selfPaths() map[string]string {
+	paths := make(map[string]string)
+	for _, line := range readLines("/proc/self/cgroup") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			paths[""] = parts[2]
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			paths[controller] = parts[2]
+		}
+	}
+	return paths
+}
+
+func readString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// readUint reads a single number. "max" and missing files read as not ok.
+func readUint(path string) (uint64, bool) {
+	v, err := strconv.ParseUint(readString(path), 10, 64)
+	return v, err == nil
+}
+
+func readLines(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// readKeyValues parses files of "key value" lines such as cpu.stat.
+func readKeyValues(path string) map[string]uint64 {
+	values := make(map[string]uint64)
+	for _, line := range readLines(path) {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			values[fields[0]], _ = strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	return values
+}
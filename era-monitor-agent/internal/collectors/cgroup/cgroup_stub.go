@@ -0,0 +1,31 @@
+//go:build !linux
+
+package cgroup
+
+import (
+	"errors"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+)
+
+var errNotSupported = errors.New("cgroups are not supported on this platform")
+
+// Read is not supported on this platform.
+func (r *Reader) Read(path string) (*api.CgroupInfo, error) {
+	return nil, errNotSupported
+}
+
+// ReadSelf is not supported on this platform.
+func (r *Reader) ReadSelf() (*api.CgroupInfo, error) {
+	return nil, errNotSupported
+}
+
+// Exists always reports false on this platform.
+func Exists(path string) bool {
+	return false
+}
+
+// ContainerPath always returns "" on this platform.
+func ContainerPath(id string) string {
+	return ""
+}
@@ -0,0 +1,90 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+)
+
+// AWSProvider reads the EC2 instance identity document, using an IMDSv2
+// session token when the instance requires one.
+type AWSProvider struct {
+	baseURL string
+}
+
+func NewAWSProvider(baseURL string) *AWSProvider {
+	return &AWSProvider{baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (p *AWSProvider) Name() string {
+	return "aws"
+}
+
+type awsIdentityDocument struct {
+	InstanceID       string `json:"instanceId"`
+	InstanceType     string `json:"instanceType"`
+	Region           string `json:"region"`
+	AvailabilityZone string `json:"availabilityZone"`
+	AccountID        string `json:"accountId"`
+	ImageID          string `json:"imageId"`
+}
+
+func (p *AWSProvider) Detect(ctx context.Context, client *http.Client, includeTags bool) (*api.CloudInfo, error) {
+	headers := map[string]string{}
+
+	// IMDSv2: fetch a session token. Instances that still allow IMDSv1 work
+	// without one, so a failure here is not fatal.
+	token, _, err := fetch(ctx, client, http.MethodPut, p.baseURL+"/latest/api/token", map[string]string{
+		"X-aws-ec2-metadata-token-ttl-seconds": "60",
+	})
+	if err == nil && len(token) > 0 {
+		headers["X-aws-ec2-metadata-token"] = strings.TrimSpace(string(token))
+	}
+
+	body, _, err := fetch(ctx, client, http.MethodGet, p.baseURL+"/latest/dynamic/instance-identity/document", headers)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc awsIdentityDocument
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, err
+	}
+	if doc.InstanceID == "" {
+		return nil, errors.New("aws identity document has no instance id")
+	}
+
+	info := &api.CloudInfo{
+		Region:       doc.Region,
+		Zone:         doc.AvailabilityZone,
+		InstanceID:   doc.InstanceID,
+		InstanceType: doc.InstanceType,
+		AccountID:    doc.AccountID,
+		ImageID:      doc.ImageID,
+	}
+
+	// Tags are only exposed when "instance metadata tags" is enabled.
+	if includeTags {
+		if keys, _, err := fetch(ctx, client, http.MethodGet, p.baseURL+"/latest/meta-data/tags/instance", headers); err == nil {
+			info.Tags = make(map[string]string)
+			for _, key := range strings.Split(strings.TrimSpace(string(keys)), "\n") {
+				key = strings.TrimSpace(key)
+				if key == "" {
+					continue
+				}
+				if value, _, err := fetch(ctx, client, http.MethodGet, p.baseURL+"/latest/meta-data/tags/instance/"+key, headers); err == nil {
+					info.Tags[key] = string(value)
+				}
+			}
+			if name, ok := info.Tags["Name"]; ok {
+				info.InstanceName = name
+			}
+		}
+	}
+
+	return info, nil
+}
@@ -0,0 +1,80 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+)
+
+// AzureProvider reads the Azure Instance Metadata Service.
+type AzureProvider struct {
+	baseURL string
+}
+
+func NewAzureProvider(baseURL string) *AzureProvider {
+	return &AzureProvider{baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (p *AzureProvider) Name() string {
+	return "azure"
+}
+
+type azureInstance struct {
+	Compute struct {
+		VMID           string `json:"vmId"`
+		VMSize         string `json:"vmSize"`
+		Location       string `json:"location"`
+		Zone           string `json:"zone"`
+		Name           string `json:"name"`
+		SubscriptionID string `json:"subscriptionId"`
+		TagsList       []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"tagsList"`
+		StorageProfile struct {
+			ImageReference struct {
+				ID string `json:"id"`
+			} `json:"imageReference"`
+		} `json:"storageProfile"`
+	} `json:"compute"`
+}
+
+func (p *AzureProvider) Detect(ctx context.Context, client *http.Client, includeTags bool) (*api.CloudInfo, error) {
+	body, _, err := fetch(ctx, client, http.MethodGet, p.baseURL+"/metadata/instance?api-version=2021-02-01", map[string]string{
+		"Metadata": "true",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var inst azureInstance
+	if err := json.Unmarshal(body, &inst); err != nil {
+		return nil, err
+	}
+	if inst.Compute.VMID == "" {
+		return nil, errors.New("azure metadata has no vm id")
+	}
+
+	info := &api.CloudInfo{
+		Region:       inst.Compute.Location,
+		Zone:         inst.Compute.Zone,
+		InstanceID:   inst.Compute.VMID,
+		InstanceType: inst.Compute.VMSize,
+		InstanceName: inst.Compute.Name,
+		AccountID:    inst.Compute.SubscriptionID,
+		ImageID:      inst.Compute.StorageProfile.ImageReference.ID,
+	}
+
+	if len(inst.Compute.TagsList) > 0 {
+		info.Tags = make(map[string]string, len(inst.Compute.TagsList))
+		for _, tag := range inst.Compute.TagsList {
+			info.Tags[tag.Name] = tag.Value
+		}
+	}
+
+	return info, nil
+}
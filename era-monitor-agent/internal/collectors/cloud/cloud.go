@@ -0,0 +1,178 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+	"github.com/eracloud/era-monitor-agent/internal/config"
+)
+
+// metadataEndpoint is the link-local address every supported provider serves
+// its instance metadata on.
+const metadataEndpoint = "http://169.254.169.254"
+
+const maxResponseBytes = 1 << 20
+
+// failureTTL bounds how long a failed detection is cached, so that a
+// metadata service that was briefly unreachable is probed again soon.
+const failureTTL = 5 * time.Minute
+
+// Provider probes one cloud's instance metadata service.
+type Provider interface {
+	Name() string
+	Detect(ctx context.Context, client *http.Client, includeTags bool) (*api.CloudInfo, error)
+}
+
+// DefaultProviders returns every supported provider in detection priority
+// order, pointed at the standard metadata endpoint.
+func DefaultProviders() []Provider {
+	return []Provider{
+		NewAWSProvider(metadataEndpoint),
+		NewAzureProvider(metadataEndpoint),
+		NewGCPProvider(metadataEndpoint),
+		NewHetznerProvider(metadataEndpoint),
+		NewOpenStackProvider(metadataEndpoint),
+	}
+}
+
+// Collector detects which cloud the host runs on. Detection is cached and
+// repeated once per refresh interval, since instance metadata rarely changes;
+// a detection that found nothing is repeated after failureTTL at the latest.
+type Collector struct {
+	providers   []Provider
+	client      *http.Client
+	refresh     time.Duration
+	includeTags bool
+
+	mu        sync.Mutex
+	info      *api.CloudInfo
+	expiresAt time.Time
+}
+
+// NewCollector creates a cloud collector for the configured providers
+func NewCollector(cfg config.CloudCollectorConfig) *Collector {
+	return NewCollectorWithProviders(cfg, filterProviders(DefaultProviders(), cfg.Providers))
+}
+
+// NewCollectorWithProviders creates a cloud collector that probes the given
+// providers, in order of priority.
+func NewCollectorWithProviders(cfg config.CloudCollectorConfig, providers []Provider) *Collector {
+	timeout := time.Duration(cfg.TimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = 500 * time.Millisecond
+	}
+	refresh := time.Duration(cfg.RefreshMinutes) * time.Minute
+	if refresh <= 0 {
+		refresh = time.Hour
+	}
+
+	return &Collector{
+		providers: providers,
+		client: &http.Client{
+			Timeout: timeout,
+			// Metadata services are link-local and must never go through a proxy.
+			Transport: &http.Transport{Proxy: nil},
+		},
+		refresh:     refresh,
+		includeTags: cfg.IncludeTags,
+	}
+}
+
+// Collect returns the detected cloud instance, or nil when the host is not
+// on a supported cloud.
+func (c *Collector) Collect(ctx context.Context) *api.CloudInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Now().Before(c.expiresAt) {
+		return c.info
+	}
+
+	c.info = c.detect(ctx)
+	ttl := c.refresh
+	if c.info == nil && ttl > failureTTL {
+		ttl = failureTTL
+	}
+	c.expiresAt = time.Now().Add(ttl)
+	return c.info
+}
+
+// detect probes all providers concurrently and returns the first match in
+// priority order.
+func (c *Collector) detect(ctx context.Context) *api.CloudInfo {
+	results := make([]*api.CloudInfo, len(c.providers))
+
+	var wg sync.WaitGroup
+	for i, p := range c.providers {
+		wg.Add(1)
+		go func(i int, p Provider) {
+			defer wg.Done()
+			info, err := p.Detect(ctx, c.client, c.includeTags)
+			if err == nil && info != nil {
+				info.Provider = p.Name()
+				results[i] = info
+			}
+		}(i, p)
+	}
+	wg.Wait()
+
+	for _, info := range results {
+		if info != nil {
+			if !c.includeTags {
+				info.Tags = nil
+			}
+			return info
+		}
+	}
+	return nil
+}
+
+func filterProviders(providers []Provider, names []string) []Provider {
+	if len(names) == 0 {
+		return providers
+	}
+
+	var result []Provider
+	for _, p := range providers {
+		for _, name := range names {
+			if strings.EqualFold(p.Name(), name) {
+				result = append(result, p)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// fetch performs a metadata request and returns the body of a 200 response.
+func fetch(ctx context.Context, client *http.Client, method, url string, headers map[string]string) ([]byte, http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("metadata request %s returned %s", url, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, resp.Header, nil
+}
@@ -0,0 +1,196 @@
+package cloud
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+	"github.com/eracloud/era-monitor-agent/internal/config"
+)
+
+var testConfig = config.CloudCollectorConfig{Enabled: true, TimeoutMs: 2000, RefreshMinutes: 60, IncludeTags: true}
+
+// metadataServer serves the given paths, each with its own handler, and
+// answers 404 to everything else like a metadata service of another cloud.
+func metadataServer(t *testing.T, routes map[string]http.HandlerFunc) string {
+	t.Helper()
+	mux := http.NewServeMux()
+	for pattern, handler := range routes {
+		mux.HandleFunc(pattern, handler)
+	}
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func reply(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}
+}
+
+// requireHeader only answers requests that carry the header with the value.
+func requireHeader(name, value string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(name) != value {
+			http.Error(w, "missing "+name, http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func TestProviders(t *testing.T) {
+	const awsToken = "AQAEAHd0b2tlbg=="
+
+	tests := []struct {
+		name     string
+		provider func(baseURL string) Provider
+		routes   map[string]http.HandlerFunc
+		want     *api.CloudInfo
+	}{
+		{
+			name:     "aws imdsv2",
+			provider: func(u string) Provider { return NewAWSProvider(u) },
+			routes: map[string]http.HandlerFunc{
+				"PUT /latest/api/token": requireHeader("X-aws-ec2-metadata-token-ttl-seconds", "60", reply(awsToken)),
+				"GET /latest/dynamic/instance-identity/document": requireHeader("X-aws-ec2-metadata-token", awsToken, reply(`{
+					"accountId": "123456789012", "availabilityZone": "eu-central-1a", "imageId": "ami-0abc",
+					"instanceId": "i-0123456789abcdef0", "instanceType": "t3.micro", "region": "eu-central-1"}`)),
+				"GET /latest/meta-data/tags/instance":      requireHeader("X-aws-ec2-metadata-token", awsToken, reply("Name\nenv\n")),
+				"GET /latest/meta-data/tags/instance/Name": requireHeader("X-aws-ec2-metadata-token", awsToken, reply("web-1")),
+				"GET /latest/meta-data/tags/instance/env":  requireHeader("X-aws-ec2-metadata-token", awsToken, reply("prod")),
+			},
+			want: &api.CloudInfo{
+				Provider: "aws", Region: "eu-central-1", Zone: "eu-central-1a", InstanceID: "i-0123456789abcdef0",
+				InstanceType: "t3.micro", InstanceName: "web-1", AccountID: "123456789012", ImageID: "ami-0abc",
+				Tags: map[string]string{"Name": "web-1", "env": "prod"},
+			},
+		},
+		{
+			name:     "azure",
+			provider: func(u string) Provider { return NewAzureProvider(u) },
+			routes: map[string]http.HandlerFunc{
+				"GET /metadata/instance": requireHeader("Metadata", "true", reply(`{"compute": {
+					"vmId": "02aab8a4-74ef-476e-8182-f6d2ba4166a6", "vmSize": "Standard_B2s", "location": "westeurope",
+					"zone": "1", "name": "vm-1", "subscriptionId": "8d10da13-8125-4ba9-a717-bf7490507b3d",
+					"tagsList": [{"name": "env", "value": "prod"}],
+					"storageProfile": {"imageReference": {"id": "/images/ubuntu"}}}}`)),
+			},
+			want: &api.CloudInfo{
+				Provider: "azure", Region: "westeurope", Zone: "1", InstanceID: "02aab8a4-74ef-476e-8182-f6d2ba4166a6",
+				InstanceType: "Standard_B2s", InstanceName: "vm-1", AccountID: "8d10da13-8125-4ba9-a717-bf7490507b3d",
+				ImageID: "/images/ubuntu", Tags: map[string]string{"env": "prod"},
+			},
+		},
+		{
+			name:     "gcp",
+			provider: func(u string) Provider { return NewGCPProvider(u) },
+			routes: map[string]http.HandlerFunc{
+				"GET /computeMetadata/v1/instance/": requireHeader("Metadata-Flavor", "Google", func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Metadata-Flavor", "Google")
+					w.Write([]byte(`{"id": 4520031799277581759, "name": "gce-1",
+						"machineType": "projects/123456/machineTypes/e2-medium",
+						"zone": "projects/123456/zones/europe-west1-b", "image": "projects/debian-cloud/global/images/debian-12"}`))
+				}),
+			},
+			want: &api.CloudInfo{
+				Provider: "gcp", Region: "europe-west1", Zone: "europe-west1-b", InstanceID: "4520031799277581759",
+				InstanceType: "e2-medium", InstanceName: "gce-1", AccountID: "123456",
+				ImageID: "projects/debian-cloud/global/images/debian-12",
+			},
+		},
+		{
+			name:     "hetzner",
+			provider: func(u string) Provider { return NewHetznerProvider(u) },
+			routes: map[string]http.HandlerFunc{
+				"GET /hetzner/v1/metadata": reply("availability-zone: fsn1-dc14\nhostname: hz-1\ninstance-id: 42424242\n" +
+					"public-ipv4: 203.0.113.10\nregion: eu-central\npublic-keys:\n- ssh-ed25519 AAAA\n" +
+					"network-config:\n  version: 1\n"),
+			},
+			want: &api.CloudInfo{
+				Provider: "hetzner", Region: "eu-central", Zone: "fsn1-dc14", InstanceID: "42424242", InstanceName: "hz-1",
+			},
+		},
+		{
+			name:     "openstack",
+			provider: func(u string) Provider { return NewOpenStackProvider(u) },
+			routes: map[string]http.HandlerFunc{
+				"GET /openstack/latest/meta_data.json": reply(`{"uuid": "d8e02d56-2648-49a3-bf97-6be8f1204f38",
+					"name": "os-1", "availability_zone": "nova", "project_id": "f7ac731cc11f40efbc03a9f9e1d1d21f",
+					"meta": {"role": "db"}}`),
+				"GET /latest/meta-data/instance-type": reply("m1.small\n"),
+			},
+			want: &api.CloudInfo{
+				Provider: "openstack", Zone: "nova", InstanceID: "d8e02d56-2648-49a3-bf97-6be8f1204f38",
+				InstanceType: "m1.small", InstanceName: "os-1", AccountID: "f7ac731cc11f40efbc03a9f9e1d1d21f",
+				Tags: map[string]string{"role": "db"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseURL := metadataServer(t, tt.routes)
+			c := NewCollectorWithProviders(testConfig, []Provider{tt.provider(baseURL)})
+			if got := c.Collect(t.Context()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Collect() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Every provider is probed; the first in priority order that answers wins,
+// and one that does not answer is not mistaken for its cloud.
+func TestCollectorPriority(t *testing.T) {
+	baseURL := metadataServer(t, map[string]http.HandlerFunc{
+		"GET /hetzner/v1/metadata":             reply("instance-id: 1\nregion: eu-central\n"),
+		"GET /openstack/latest/meta_data.json": reply(`{"uuid": "abc"}`),
+	})
+	providers := []Provider{
+		NewAWSProvider(baseURL),
+		NewGCPProvider(baseURL),
+		NewOpenStackProvider(baseURL),
+		NewHetznerProvider(baseURL),
+	}
+	got := NewCollectorWithProviders(testConfig, providers).Collect(t.Context())
+	if got == nil || got.Provider != "openstack" {
+		t.Errorf("Collect() = %+v, want openstack", got)
+	}
+}
+
+func TestCollectorCache(t *testing.T) {
+	calls := 0
+	baseURL := metadataServer(t, map[string]http.HandlerFunc{
+		"GET /hetzner/v1/metadata": func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 1 {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte("instance-id: 1\n"))
+		},
+	})
+	c := NewCollectorWithProviders(testConfig, []Provider{NewHetznerProvider(baseURL)})
+
+	if got := c.Collect(t.Context()); got != nil {
+		t.Fatalf("Collect() = %+v, want nil while the metadata service fails", got)
+	}
+	if left := time.Until(c.expiresAt); left > failureTTL {
+		t.Fatalf("a failed detection is cached for %v, want at most %v", left, failureTTL)
+	}
+
+	c.expiresAt = time.Now()
+	if got := c.Collect(t.Context()); got == nil || got.InstanceID != "1" {
+		t.Fatalf("Collect() = %+v after the retry, want instance 1", got)
+	}
+	if left := time.Until(c.expiresAt); left <= failureTTL {
+		t.Errorf("a detection is cached for %v, want the refresh interval", left)
+	}
+	c.Collect(t.Context())
+	if calls != 2 {
+		t.Errorf("metadata requested %d times, want 2", calls)
+	}
+}
@@ -0,0 +1,72 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+)
+
+// GCPProvider reads the Google Compute Engine metadata server.
+type GCPProvider struct {
+	baseURL string
+}
+
+func NewGCPProvider(baseURL string) *GCPProvider {
+	return &GCPProvider{baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (p *GCPProvider) Name() string {
+	return "gcp"
+}
+
+type gcpInstance struct {
+	ID          json.Number `json:"id"`
+	Name        string      `json:"name"`
+	MachineType string      `json:"machineType"`
+	Zone        string      `json:"zone"`
+	Image       string      `json:"image"`
+}
+
+func (p *GCPProvider) Detect(ctx context.Context, client *http.Client, includeTags bool) (*api.CloudInfo, error) {
+	body, header, err := fetch(ctx, client, http.MethodGet, p.baseURL+"/computeMetadata/v1/instance/?recursive=true", map[string]string{
+		"Metadata-Flavor": "Google",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if header.Get("Metadata-Flavor") != "Google" {
+		return nil, errors.New("not a gce metadata server")
+	}
+
+	var inst gcpInstance
+	if err := json.Unmarshal(body, &inst); err != nil {
+		return nil, err
+	}
+	if inst.ID == "" {
+		return nil, errors.New("gce metadata has no instance id")
+	}
+
+	// Zone and machine type come as resource paths such as
+	// "projects/123456/zones/europe-west1-b".
+	zone := path.Base(inst.Zone)
+	info := &api.CloudInfo{
+		Zone:         zone,
+		InstanceID:   inst.ID.String(),
+		InstanceType: path.Base(inst.MachineType),
+		InstanceName: inst.Name,
+		ImageID:      inst.Image,
+	}
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		info.Region = zone[:i]
+	}
+	if parts := strings.Split(inst.Zone, "/"); len(parts) >= 2 && parts[0] == "projects" {
+		info.AccountID = parts[1]
+	}
+
+	return info, nil
+}
@@ -0,0 +1,67 @@
+package cloud
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+)
+
+// HetznerProvider reads the Hetzner Cloud metadata service.
+type HetznerProvider struct {
+	baseURL string
+}
+
+func NewHetznerProvider(baseURL string) *HetznerProvider {
+	return &HetznerProvider{baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (p *HetznerProvider) Name() string {
+	return "hetzner"
+}
+
+func (p *HetznerProvider) Detect(ctx context.Context, client *http.Client, includeTags bool) (*api.CloudInfo, error) {
+	body, _, err := fetch(ctx, client, http.MethodGet, p.baseURL+"/hetzner/v1/metadata", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := parseTopLevelYAML(body)
+	if fields["instance-id"] == "" {
+		return nil, errors.New("hetzner metadata has no instance id")
+	}
+
+	return &api.CloudInfo{
+		Region:       fields["region"],
+		Zone:         fields["availability-zone"],
+		InstanceID:   fields["instance-id"],
+		InstanceName: fields["hostname"],
+	}, nil
+}
+
+// parseTopLevelYAML extracts the scalar "key: value" pairs at the top level
+// of a YAML document. Nested blocks and lists are skipped.
+func parseTopLevelYAML(data []byte) map[string]string {
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == ' ' || line[0] == '\t' || line[0] == '-' || line[0] == '#' {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		if value == "" {
+			continue
+		}
+		fields[strings.TrimSpace(key)] = value
+	}
+	return fields
+}
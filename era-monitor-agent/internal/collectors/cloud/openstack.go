@@ -0,0 +1,62 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+)
+
+// OpenStackProvider reads the OpenStack Nova metadata service.
+type OpenStackProvider struct {
+	baseURL string
+}
+
+func NewOpenStackProvider(baseURL string) *OpenStackProvider {
+	return &OpenStackProvider{baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (p *OpenStackProvider) Name() string {
+	return "openstack"
+}
+
+type openStackMetadata struct {
+	UUID             string            `json:"uuid"`
+	Name             string            `json:"name"`
+	AvailabilityZone string            `json:"availability_zone"`
+	ProjectID        string            `json:"project_id"`
+	Meta             map[string]string `json:"meta"`
+}
+
+func (p *OpenStackProvider) Detect(ctx context.Context, client *http.Client, includeTags bool) (*api.CloudInfo, error) {
+	body, _, err := fetch(ctx, client, http.MethodGet, p.baseURL+"/openstack/latest/meta_data.json", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var meta openStackMetadata
+	if err := json.Unmarshal(body, &meta); err != nil {
+		return nil, err
+	}
+	if meta.UUID == "" {
+		return nil, errors.New("openstack metadata has no uuid")
+	}
+
+	info := &api.CloudInfo{
+		Zone:         meta.AvailabilityZone,
+		InstanceID:   meta.UUID,
+		InstanceName: meta.Name,
+		AccountID:    meta.ProjectID,
+		Tags:         meta.Meta,
+	}
+
+	// The flavor is only exposed through the EC2-compatible API.
+	if flavor, _, err := fetch(ctx, client, http.MethodGet, p.baseURL+"/latest/meta-data/instance-type", nil); err == nil {
+		info.InstanceType = strings.TrimSpace(string(flavor))
+	}
+
+	return info, nil
+}
@@ -0,0 +1,193 @@
+// Package eventlog collects critical system events: the Windows event log
+// on Windows, and the systemd journal or syslog on Linux. Every platform
+// reads its sources through the same pipeline, which resumes each source
+// from a persisted bookmark, drops duplicates and folds floods into summary
+// events.
+package eventlog
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/eracloud/era-monitor-agent/internal/config"
+)
+
+// EventInfo represents a single event log entry. A summary of a flood has
+// Occurrences set, FirstOccurrence and TimeCreated spanning the flood, and
+// the message of its first event.
+type EventInfo struct {
+	LogName     string    `json:"logName"`
+	EventID     int       `json:"eventId"`
+	Level       string    `json:"level"`
+	Source      string    `json:"source"`
+	Message     string    `json:"message"`
+	TimeCreated time.Time `json:"timeCreated"`
+	Category    string    `json:"category"`
+
+	// RecordID is the event's sequence number in its log, where the log
+	// has one.
+	RecordID uint64 `json:"recordId,omitempty"`
+
+	Occurrences     int       `json:"occurrences,omitempty"`
+	FirstOccurrence time.Time `json:"firstOccurrence"`
+}
+
+// maxEvents caps the number of events returned by one Collect call. Events
+// beyond it are summarised rather than dropped.
+const maxEvents = 1000
+
+// source is one log the pipeline reads. read returns the events after
+// bookmark, which is empty on the very first read, and the bookmark to
+// resume from next time.
+type source interface {
+	name() string
+	read(bookmark string) ([]EventInfo, string, error)
+}
+
+// Collector reads all event sources of the platform
+type Collector struct {
+	enabled bool
+	sources []source
+
+	mu        sync.Mutex
+	bookmarks *bookmarkStore
+	positions map[string]string
+	pending   []checkpoint
+	lastID    Checkpoint
+	dedup     *dedup
+	limiter   *limiter
+}
+
+// Checkpoint identifies the read positions after one Collect call. Zero is
+// no checkpoint.
+type Checkpoint uint64
+
+// checkpoint holds the read positions of a Collect call until its events
+// have been delivered.
+type checkpoint struct {
+	id        Checkpoint
+	marks     map[string]string
+	delivered bool
+}
+
+// NewCollector creates a new event log collector
+func NewCollector(enabled bool, cfg config.EventsConfig) *Collector {
+	if cfg.BookmarkPath == "" {
+		cfg.BookmarkPath = config.DefaultEventBookmarkPath()
+	}
+	return &Collector{
+		enabled:   enabled,
+		sources:   platformSources(),
+		bookmarks: newBookmarkStore(cfg.BookmarkPath),
+		positions: make(map[string]string),
+		dedup:     newDedup(dedupSize),
+		limiter:   newLimiter(cfg.RateLimitPerMinute),
+	}
+}
+
+// Collect returns the events logged since the previous call, or since the
+// bookmarks saved by the previous run of the agent, with the checkpoint to
+// pass to Commit once they have been delivered. The persisted bookmarks
+// only move past events that were committed, so events still waiting to be
+// sent are read again after a restart. A source that fails does not hold
+// back the events of the others; its error is returned alongside them.
+func (c *Collector) Collect() ([]EventInfo, Checkpoint, error) {
+	if !c.enabled {
+		return nil, 0, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var events []EventInfo
+	var errs []error
+	for _, src := range c.sources {
+		name := src.name()
+		position, ok := c.positions[name]
+		if !ok {
+			position = c.bookmarks.Get(name)
+		}
+		read, next, err := src.read(position)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+		if next != "" {
+			c.positions[name] = next
+		}
+		events = append(events, read...)
+	}
+	events = c.process(events, time.Now())
+
+	c.lastID++
+	cp := checkpoint{
+		id:        c.lastID,
+		marks:     make(map[string]string, len(c.positions)),
+		delivered: len(events) == 0,
+	}
+	for name, position := range c.positions {
+		cp.marks[name] = position
+	}
+	c.pending = append(c.pending, cp)
+
+	if err := c.advance(); err != nil {
+		errs = append(errs, err)
+	}
+	return events, cp.id, errors.Join(errs...)
+}
+
+// Commit records that the events of checkpoint id, and of every checkpoint
+// before it, have been delivered, and persists the bookmarks as far as
+// everything up to them has been.
+func (c *Collector) Commit(id Checkpoint) error {
+	if id == 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := range c.pending {
+		if c.pending[i].id <= id {
+			c.pending[i].delivered = true
+		}
+	}
+	return c.advance()
+}
+
+// advance saves the read positions of the newest checkpoint that has no
+// undelivered checkpoint before it.
+func (c *Collector) advance() error {
+	n := 0
+	for n < len(c.pending) && c.pending[n].delivered {
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+	for name, position := range c.pending[n-1].marks {
+		c.bookmarks.Set(name, position)
+	}
+	c.pending = c.pending[n:]
+	return c.bookmarks.Save()
+}
+
+// process drops duplicates and passes the rest through the rate limiter.
+// Whatever the limiter holds back, and everything beyond maxEvents, comes
+// out as summaries.
+func (c *Collector) process(events []EventInfo, now time.Time) []EventInfo {
+	var result []EventInfo
+	floods := newFloodSet()
+	for _, e := range events {
+		if c.dedup.Seen(e) {
+			continue
+		}
+		if !c.limiter.Allow(e, now) || len(result) >= maxEvents {
+			floods.Add(e)
+			continue
+		}
+		result = append(result, e)
+	}
+	return append(result, floods.Summaries()...)
+}
@@ -0,0 +1,499 @@
+//go:build linux
+
+package eventlog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/eracloud/era-monitor-agent/internal/collectors/hostfs"
+)
+
+// Event IDs of the Linux events. Linux has no event IDs of its own, so each
+// kind of event gets a fixed number the server can filter on.
+const (
+	eventOOMKill          = 1
+	eventKernelPanic      = 2
+	eventKernelOops       = 3
+	eventSegfault         = 4
+	eventIOError          = 5
+	eventFilesystemError  = 6
+	eventUnexpectedReboot = 7
+)
+
+// criticalEvents are matched against kernel messages, case-insensitively.
+// The first matching rule wins. Userspace faults come before the oops rule
+// because the kernel reports them as "traps: app[123] general protection
+// fault ip:...", which must not be taken for a kernel oops.
+var criticalEvents = []struct {
+	eventID  int
+	category string
+	level    string
+	patterns []string
+}{
+	{eventOOMKill, "System", "Error", []string{"out of memory: kill", "oom-kill:", "memory cgroup out of memory"}},
+	{eventKernelPanic, "System", "Critical", []string{"kernel panic"}},
+	{eventSegfault, "System", "Warning", []string{" segfault at ", "traps: "}},
+	{eventKernelOops, "System", "Critical", []string{"oops:", "kernel bug at", "bug: unable to handle", "general protection fault, probably for non-canonical address", "general protection fault: ", "watchdog: bug: soft lockup", "hung_task"}},
+	{eventIOError, "Disk", "Error", []string{"i/o error", "critical medium error", "blk_update_request: "}},
+	{eventFilesystemError, "Disk", "Error", []string{"ext4-fs error", "metadata corruption detected", "xfs_do_force_shutdown", "btrfs error", "remounting filesystem read-only", "fsck needed"}},
+}
+
+// cleanStopMarkers are logged during an orderly shutdown. A boot that was
+// not preceded by one of them was an unexpected reboot.
+var cleanStopMarkers = []string{
+	"journal stopped",
+	"systemd-shutdown",
+	"exiting on signal",
+	"reached target shutdown",
+	"reached target system power off",
+	"reached target system reboot",
+	"reached target power-off",
+	"reached target reboot",
+}
+
+var (
+	journalDirs = []string{"/var/log/journal", "/run/log/journal"}
+	syslogFiles = []string{"/var/log/syslog", "/var/log/messages", "/var/log/kern.log"}
+)
+
+const (
+	journalTimeout = 30 * time.Second
+	// syslogInitialRead limits how much of an existing syslog file is read
+	// when there is no bookmark yet.
+	syslogInitialRead = 4 << 20
+	// syslogMaxRead limits how much is read per cycle after that.
+	syslogMaxRead = 16 << 20
+)
+
+// platformSources reads kernel messages from the systemd journal, or from
+// the syslog files when there is no journal.
+func platformSources() []source {
+	if dir, ok := journalDir(); ok {
+		return []source{&bootSource{dir: dir}, &journalSource{dir: dir}}
+	}
+	if path, ok := syslogFile(); ok {
+		return []source{&syslogSource{path: path}}
+	}
+	return nil
+}
+
+// journalEntry holds the journal export fields used here. MESSAGE is a
+// string, or an array of bytes when it is not valid UTF-8.
+type journalEntry struct {
+	Cursor     string          `json:"__CURSOR"`
+	Realtime   string          `json:"__REALTIME_TIMESTAMP"`
+	Message    json.RawMessage `json:"MESSAGE"`
+	Identifier string          `json:"SYSLOG_IDENTIFIER"`
+}
+
+func (e *journalEntry) text() string {
+	var s string
+	if err := json.Unmarshal(e.Message, &s); err == nil {
+		return s
+	}
+	var b []byte
+	if err := json.Unmarshal(e.Message, &b); err == nil {
+		return string(b)
+	}
+	return ""
+}
+
+func (e *journalEntry) time() time.Time {
+	usec, _ := strconv.ParseInt(e.Realtime, 10, 64)
+	return time.UnixMicro(usec).UTC()
+}
+
+// journalSource reads kernel messages from the journal. Its bookmark is the
+// journal cursor of the last message read; without one it starts at the
+// beginning of the current boot.
+type journalSource struct {
+	dir string
+}
+
+func (s *journalSource) name() string {
+	return "journal"
+}
+
+func (s *journalSource) read(bookmark string) ([]EventInfo, string, error) {
+	args := []string{"--boot"}
+	if bookmark != "" {
+		args = []string{"--after-cursor", bookmark}
+	}
+	events, cursor, err := s.readFrom(args, bookmark)
+
+	// A cursor into journal files that were vacuumed since is rejected.
+	// Resume from the time it points at rather than failing forever; other
+	// errors are returned so that a transient failure does not cause the
+	// whole boot to be read again.
+	if err != nil && bookmark != "" && cursor == bookmark && strings.Contains(err.Error(), "cursor") {
+		args = []string{"--boot"}
+		if t, ok := cursorTime(bookmark); ok {
+			args = []string{"--since", "@" + strconv.FormatInt(t.Unix(), 10)}
+		}
+		return s.readFrom(args, bookmark)
+	}
+	return events, cursor, err
+}
+
+func (s *journalSource) readFrom(args []string, bookmark string) ([]EventInfo, string, error) {
+	var events []EventInfo
+	cursor := bookmark
+	err := readJournal(s.dir, append([]string{"_TRANSPORT=kernel"}, args...), func(e *journalEntry) {
+		cursor = e.Cursor
+		if event, ok := match(e.text()); ok {
+			event.LogName = "journal"
+			event.Source = e.Identifier
+			event.TimeCreated = e.time()
+			events = append(events, event)
+		}
+	})
+	return events, cursor, err
+}
+
+// cursorTime returns the realtime timestamp of a journal cursor, the hex
+// microseconds of its "t=" field.
+func cursorTime(cursor string) (time.Time, bool) {
+	for _, field := range strings.Split(cursor, ";") {
+		if v, ok := strings.CutPrefix(field, "t="); ok {
+			usec, err := strconv.ParseInt(v, 16, 64)
+			if err != nil {
+				return time.Time{}, false
+			}
+			return time.UnixMicro(usec).UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// bootSource reports an unexpected reboot when the last messages of the
+// previous boot contain no sign of an orderly shutdown. It needs a
+// persistent journal; with a volatile one there is no previous boot. The
+// previous boot is checked once per run, and its bookmark, the time of its
+// last message, keeps a restart of the agent from reporting it again.
+type bootSource struct {
+	dir     string
+	checked bool
+}
+
+func (s *bootSource) name() string {
+	return "journal-boot"
+}
+
+func (s *bootSource) read(bookmark string) ([]EventInfo, string, error) {
+	if s.checked {
+		return nil, bookmark, nil
+	}
+	s.checked = true
+
+	var last *journalEntry
+	clean := false
+	err := readJournal(s.dir, []string{"--boot=-1", "--lines=50"}, func(e *journalEntry) {
+		if containsAny(strings.ToLower(e.text()), cleanStopMarkers) {
+			clean = true
+		}
+		last = e
+	})
+	if err != nil || last == nil {
+		return nil, bookmark, nil
+	}
+
+	ended := last.time()
+	next := ended.Format(time.RFC3339Nano)
+	if clean || next == bookmark {
+		return nil, next, nil
+	}
+
+	return []EventInfo{{
+		LogName:     "journal",
+		EventID:     eventUnexpectedReboot,
+		Level:       "Critical",
+		Source:      "kernel",
+		Message:     fmt.Sprintf("The previous boot ended without an orderly shutdown; its last message was logged at %s", ended.Format(time.RFC3339)),
+		TimeCreated: ended,
+		Category:    "System",
+	}}, next, nil
+}
+
+func readJournal(dir string, args []string, fn func(*journalEntry)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), journalTimeout)
+	defer cancel()
+
+	args = append([]string{"--output=json", "--no-pager", "--quiet"}, args...)
+	if hostfs.Enabled() {
+		args = append([]string{"--directory", dir}, args...)
+	}
+
+	cmd := exec.CommandContext(ctx, "journalctl", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var e journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err == nil {
+			fn(&e)
+		}
+	}
+	io.Copy(io.Discard, stdout)
+
+	if err := cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("journalctl failed: %w: %s", err, msg)
+		}
+		return fmt.Errorf("journalctl failed: %w", err)
+	}
+	return nil
+}
+
+// syslogSource reads kernel messages from a syslog file. Its bookmark is
+// the inode and offset up to which the file was read. Without one only the
+// tail of the file is read.
+type syslogSource struct {
+	path string
+
+	// seen is set once a line was read in this run, and cleanStop once an
+	// orderly shutdown was logged since the last boot.
+	seen      bool
+	cleanStop bool
+}
+
+func (s *syslogSource) name() string {
+	return "syslog"
+}
+
+func (s *syslogSource) read(bookmark string) ([]EventInfo, string, error) {
+	f, err := os.Open(hostfs.Root(s.path))
+	if err != nil {
+		return nil, bookmark, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, bookmark, err
+	}
+	var inode uint64
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		inode = st.Ino
+	}
+
+	// Start over when the file was rotated or truncated.
+	var offset int64
+	tail := false
+	if markInode, markOffset, ok := parseSyslogBookmark(bookmark); !ok {
+		offset = info.Size() - syslogInitialRead
+		tail = offset > 0
+		if offset < 0 {
+			offset = 0
+		}
+	} else if markInode == inode && markOffset <= info.Size() {
+		offset = markOffset
+	}
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, bookmark, err
+	}
+	data, err := io.ReadAll(io.LimitReader(f, syslogMaxRead))
+	if err != nil {
+		return nil, bookmark, err
+	}
+
+	// Only complete lines are consumed; a partial last line is read again
+	// next time.
+	end := bytes.LastIndexByte(data, '\n')
+	if end < 0 {
+		return nil, formatSyslogBookmark(inode, offset), nil
+	}
+	data = data[:end+1]
+	next := formatSyslogBookmark(inode, offset+int64(len(data)))
+	path := s.path
+
+	var events []EventInfo
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	for i, line := range lines {
+		// The first line of a read started mid-file may be cut off.
+		if i == 0 && tail {
+			continue
+		}
+
+		ts, source, message, ok := parseSyslogLine(line)
+		if !ok {
+			continue
+		}
+		lower := strings.ToLower(message)
+
+		if containsAny(lower, cleanStopMarkers) {
+			s.cleanStop = true
+		}
+		if source == "kernel" && strings.Contains(message, "Linux version ") {
+			if s.seen && !s.cleanStop {
+				events = append(events, EventInfo{
+					LogName:     path,
+					EventID:     eventUnexpectedReboot,
+					Level:       "Critical",
+					Source:      source,
+					Message:     "The system booted without an orderly shutdown of the previous boot",
+					TimeCreated: ts,
+					Category:    "System",
+				})
+			}
+			s.cleanStop = false
+		}
+		s.seen = true
+
+		if source != "kernel" {
+			continue
+		}
+		if event, ok := match(message); ok {
+			event.LogName = path
+			event.Source = source
+			event.TimeCreated = ts
+			events = append(events, event)
+		}
+	}
+	return events, next, nil
+}
+
+func formatSyslogBookmark(inode uint64, offset int64) string {
+	return strconv.FormatUint(inode, 10) + ":" + strconv.FormatInt(offset, 10)
+}
+
+func parseSyslogBookmark(bookmark string) (uint64, int64, bool) {
+	inodeText, offsetText, ok := strings.Cut(bookmark, ":")
+	if !ok {
+		return 0, 0, false
+	}
+	inode, err1 := strconv.ParseUint(inodeText, 10, 64)
+	offset, err2 := strconv.ParseInt(offsetText, 10, 64)
+	return inode, offset, err1 == nil && err2 == nil
+}
+
+// parseSyslogLine splits a syslog line in either the RFC 3339 format,
+// "2024-10-19T10:00:00.123456+00:00 host kernel: msg", or the traditional
+// format, "Oct 19 10:00:00 host kernel: msg".
+func parseSyslogLine(line string) (time.Time, string, string, bool) {
+	fields, rest, ok := splitFields(line, 2)
+	if !ok {
+		return time.Time{}, "", "", false
+	}
+
+	ts, err := time.Parse(time.RFC3339Nano, fields[0])
+	if err != nil {
+		fields, rest, ok = splitFields(line, 4)
+		if !ok {
+			return time.Time{}, "", "", false
+		}
+		ts, err = parseTraditionalTime(strings.Join(fields[:3], " "), time.Now())
+		if err != nil {
+			return time.Time{}, "", "", false
+		}
+	}
+
+	tag, message, ok := strings.Cut(rest, ": ")
+	if !ok {
+		return time.Time{}, "", "", false
+	}
+	if i := strings.IndexByte(tag, '['); i > 0 {
+		tag = tag[:i]
+	}
+	return ts.UTC(), tag, message, true
+}
+
+// splitFields returns the first n whitespace separated fields of line and
+// the text after them, starting at the next field.
+func splitFields(line string, n int) ([]string, string, bool) {
+	fields := make([]string, 0, n)
+	rest := line
+	for len(fields) < n {
+		rest = strings.TrimLeft(rest, " \t")
+		end := strings.IndexAny(rest, " \t")
+		if rest == "" || end < 0 {
+			return nil, "", false
+		}
+		fields = append(fields, rest[:end])
+		rest = rest[end:]
+	}
+	return fields, strings.TrimLeft(rest, " \t"), true
+}
+
+// parseTraditionalTime parses a timestamp without a year, assuming the most
+// recent matching date as of now.
+func parseTraditionalTime(s string, now time.Time) (time.Time, error) {
+	ts, err := time.ParseInLocation("Jan _2 15:04:05 2006", s+" "+strconv.Itoa(now.Year()), now.Location())
+	if err != nil {
+		return time.Time{}, err
+	}
+	if ts.After(now.Add(24 * time.Hour)) {
+		ts = ts.AddDate(-1, 0, 0)
+	}
+	return ts, nil
+}
+
+// match returns the event for a kernel message that matches one of the
+// critical patterns.
+func match(message string) (EventInfo, bool) {
+	lower := strings.ToLower(message)
+	for _, rule := range criticalEvents {
+		if containsAny(lower, rule.patterns) {
+			return EventInfo{
+				EventID:  rule.eventID,
+				Level:    rule.level,
+				Message:  message,
+				Category: rule.category,
+			}, true
+		}
+	}
+	return EventInfo{}, false
+}
+
+func containsAny(s string, patterns []string) bool {
+	for _, p := range patterns {
+		if strings.Contains(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// journalDir returns the journal directory when the journal has any files
+// and journalctl is available to read them.
+func journalDir() (string, bool) {
+	if _, err := exec.LookPath("journalctl"); err != nil {
+		return "", false
+	}
+	for _, dir := range journalDirs {
+		dir = hostfs.Root(dir)
+		if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+func syslogFile() (string, bool) {
+	for _, path := range syslogFiles {
+		if _, err := os.Stat(hostfs.Root(path)); err == nil {
+			return path, true
+		}
+	}
+	return "", false
+}
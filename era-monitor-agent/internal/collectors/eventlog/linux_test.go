@@ -0,0 +1,178 @@
+//go:build linux
+
+package eventlog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSyslogLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		ok      bool
+		time    string // RFC 3339, or "Jan _2 15:04:05" local time for the traditional format
+		tag     string
+		message string
+	}{
+		{
+			name:    "rfc3339",
+			line:    "2024-10-19T10:00:00.123456+02:00 host kernel: Out of memory: Killed process 42",
+			ok:      true,
+			time:    "2024-10-19T08:00:00.123456Z",
+			tag:     "kernel",
+			message: "Out of memory: Killed process 42",
+		},
+		{
+			name:    "rfc3339 tab after hostname",
+			line:    "2024-10-19T10:00:00Z host\tkernel: msg",
+			ok:      true,
+			time:    "2024-10-19T10:00:00Z",
+			tag:     "kernel",
+			message: "msg",
+		},
+		{
+			name:    "traditional",
+			line:    "Oct 19 10:00:00 host kernel: msg",
+			ok:      true,
+			time:    "Oct 19 10:00:00",
+			tag:     "kernel",
+			message: "msg",
+		},
+		{
+			name:    "traditional padded day",
+			line:    "Oct  5 10:00:00 host kernel: msg",
+			ok:      true,
+			time:    "Oct  5 10:00:00",
+			tag:     "kernel",
+			message: "msg",
+		},
+		{
+			name:    "traditional tab after hostname",
+			line:    "Oct 19 10:00:00 host\tkernel: msg",
+			ok:      true,
+			time:    "Oct 19 10:00:00",
+			tag:     "kernel",
+			message: "msg",
+		},
+		{
+			name:    "hostname appears in timestamp",
+			line:    "Oct  5 10:05:00 05 kernel: msg",
+			ok:      true,
+			time:    "Oct  5 10:05:00",
+			tag:     "kernel",
+			message: "msg",
+		},
+		{
+			name:    "tag with pid",
+			line:    "Oct 19 10:00:00 host sshd[123]: Accepted publickey",
+			ok:      true,
+			time:    "Oct 19 10:00:00",
+			tag:     "sshd",
+			message: "Accepted publickey",
+		},
+		{name: "no tag", line: "Oct 19 10:00:00 host message without a tag"},
+		{name: "too short", line: "Oct 19 10:00:00"},
+		{name: "bad timestamp", line: "yesterday at noon host kernel: msg"},
+		{name: "empty", line: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, tag, message, ok := parseSyslogLine(tt.line)
+			if ok != tt.ok {
+				t.Fatalf("parseSyslogLine(%q) ok = %v, want %v", tt.line, ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			got := ts.Format(time.RFC3339Nano)
+			if _, err := time.Parse(time.RFC3339Nano, tt.time); err != nil {
+				got = ts.Local().Format(time.Stamp)
+			}
+			if got != tt.time || tag != tt.tag || message != tt.message {
+				t.Errorf("parseSyslogLine(%q) = %s, %q, %q; want %s, %q, %q", tt.line, got, tag, message, tt.time, tt.tag, tt.message)
+			}
+		})
+	}
+}
+
+func TestParseTraditionalTime(t *testing.T) {
+	now := time.Date(2024, time.January, 2, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"Jan  2 11:00:00", time.Date(2024, time.January, 2, 11, 0, 0, 0, time.UTC)},
+		// Up to a day ahead is taken as clock skew, not as last year.
+		{"Jan  3 11:00:00", time.Date(2024, time.January, 3, 11, 0, 0, 0, time.UTC)},
+		{"Dec 31 23:59:59", time.Date(2023, time.December, 31, 23, 59, 59, 0, time.UTC)},
+		{"Feb 10 08:00:00", time.Date(2023, time.February, 10, 8, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := parseTraditionalTime(tt.in, now)
+		if err != nil {
+			t.Errorf("parseTraditionalTime(%q) error: %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseTraditionalTime(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := parseTraditionalTime("19 Oct 10:00:00", now); err == nil {
+		t.Error("parseTraditionalTime accepted a malformed timestamp")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		message string
+		eventID int // 0 when the message must not match
+	}{
+		{"Out of memory: Killed process 1234 (java)", eventOOMKill},
+		{"Memory cgroup out of memory: Killed process 99 (php-fpm)", eventOOMKill},
+		{"Kernel panic - not syncing: Fatal exception", eventKernelPanic},
+		{"app[123]: segfault at 0 ip 00007f sp 00007ffd error 4 in libc.so.6", eventSegfault},
+		// A userspace fault names "general protection fault" too and must
+		// not be taken for a kernel oops.
+		{"traps: app[123] general protection fault ip:7f1234 sp:7ffd error:0 in libc.so.6", eventSegfault},
+		{"general protection fault, probably for non-canonical address 0xdead: 0000 [#1] SMP", eventKernelOops},
+		{"general protection fault: 0000 [#1] SMP PTI", eventKernelOops},
+		{"Oops: 0002 [#1] PREEMPT SMP", eventKernelOops},
+		{"watchdog: BUG: soft lockup - CPU#3 stuck for 22s!", eventKernelOops},
+		{"blk_update_request: I/O error, dev sda, sector 1234", eventIOError},
+		{"EXT4-fs error (device sda1): ext4_find_entry:1455: inode #2", eventFilesystemError},
+		{"XFS (sdb1): Metadata corruption detected at xfs_inode_buf_verify", eventFilesystemError},
+		{"e1000e: eth0 NIC Link is Up 1000 Mbps Full Duplex", 0},
+	}
+	for _, tt := range tests {
+		event, ok := match(tt.message)
+		if ok != (tt.eventID != 0) || event.EventID != tt.eventID {
+			t.Errorf("match(%q) = event %d, %v; want %d", tt.message, event.EventID, ok, tt.eventID)
+		}
+	}
+}
+
+func TestCursorTime(t *testing.T) {
+	tests := []struct {
+		cursor string
+		want   time.Time
+		ok     bool
+	}{
+		{
+			cursor: "s=739ad463348b4ceca5a9e69c95a3c93f;i=4ece7;b=6c7c6013a8194c4ca83ae2db1d8f1f3a;m=2e5e1b4d;t=5b6f1e3c3f5d0;x=8a7b0d2d1e9c0e7a",
+			want:   time.UnixMicro(0x5b6f1e3c3f5d0).UTC(),
+			ok:     true,
+		},
+		{cursor: "s=abc;i=1;t=zz;x=1"},
+		{cursor: "s=abc;i=1;x=1"},
+		{cursor: ""},
+	}
+	for _, tt := range tests {
+		got, ok := cursorTime(tt.cursor)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("cursorTime(%q) = %v, %v; want %v, %v", tt.cursor, got, ok, tt.want, tt.ok)
+		}
+	}
+}
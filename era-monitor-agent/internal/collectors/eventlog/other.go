@@ -0,0 +1,9 @@
+//go:build !windows && !linux
+
+package eventlog
+
+// platformSources returns no sources; this platform has no supported event
+// log.
+func platformSources() []source {
+	return nil
+}
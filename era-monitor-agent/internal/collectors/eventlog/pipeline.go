@@ -0,0 +1,176 @@
+package eventlog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// dedupSize is the number of recent events remembered for deduplication.
+const dedupSize = 10000
+
+// bookmarkStore keeps the bookmark of every source in a JSON file, so the
+// agent resumes where it stopped after a restart.
+type bookmarkStore struct {
+	path  string
+	marks map[string]string
+	dirty bool
+}
+
+func newBookmarkStore(path string) *bookmarkStore {
+	s := &bookmarkStore{path: path, marks: make(map[string]string)}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &s.marks)
+	}
+	return s
+}
+
+func (s *bookmarkStore) Get(source string) string {
+	return s.marks[source]
+}
+
+func (s *bookmarkStore) Set(source, bookmark string) {
+	if s.marks[source] != bookmark {
+		s.marks[source] = bookmark
+		s.dirty = true
+	}
+}
+
+// Save writes the bookmarks if they changed. The file is replaced
+// atomically so a crash never leaves a truncated file behind.
+func (s *bookmarkStore) Save() error {
+	if !s.dirty {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.marks, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to save event bookmarks: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to save event bookmarks: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to save event bookmarks: %w", err)
+	}
+	s.dirty = false
+	return nil
+}
+
+// dedup remembers the keys of the last size events. Events with a record ID
+// are keyed by log and record ID, others by a hash of their content.
+type dedup struct {
+	size int
+	keys map[string]bool
+	ring []string
+	next int
+}
+
+func newDedup(size int) *dedup {
+	return &dedup{size: size, keys: make(map[string]bool, size)}
+}
+
+// Seen reports whether e was seen before, and remembers it otherwise.
+func (d *dedup) Seen(e EventInfo) bool {
+	key := eventKey(e)
+	if d.keys[key] {
+		return true
+	}
+
+	if len(d.ring) < d.size {
+		d.ring = append(d.ring, key)
+	} else {
+		delete(d.keys, d.ring[d.next])
+		d.ring[d.next] = key
+		d.next = (d.next + 1) % d.size
+	}
+	d.keys[key] = true
+	return false
+}
+
+func eventKey(e EventInfo) string {
+	if e.RecordID != 0 {
+		return e.LogName + "#" + strconv.FormatUint(e.RecordID, 10)
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%d\x00%d\x00%s",
+		e.LogName, e.Source, e.EventID, e.TimeCreated.UnixNano(), e.Message)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// limiter lets through at most perMinute events per log, source and event
+// ID in every minute. Zero disables it.
+type limiter struct {
+	perMinute int
+	window    time.Time
+	counts    map[string]int
+}
+
+func newLimiter(perMinute int) *limiter {
+	return &limiter{perMinute: perMinute, counts: make(map[string]int)}
+}
+
+func (l *limiter) Allow(e EventInfo, now time.Time) bool {
+	if l.perMinute <= 0 {
+		return true
+	}
+	if window := now.Truncate(time.Minute); !window.Equal(l.window) {
+		l.window = window
+		l.counts = make(map[string]int)
+	}
+	key := floodKey(e)
+	l.counts[key]++
+	return l.counts[key] <= l.perMinute
+}
+
+func floodKey(e EventInfo) string {
+	return e.LogName + "\x00" + e.Source + "\x00" + strconv.Itoa(e.EventID)
+}
+
+// floodSet collects held back events into one summary per log, source and
+// event ID.
+type floodSet struct {
+	order  []string
+	floods map[string]*EventInfo
+}
+
+func newFloodSet() *floodSet {
+	return &floodSet{floods: make(map[string]*EventInfo)}
+}
+
+func (f *floodSet) Add(e EventInfo) {
+	key := floodKey(e)
+	s, ok := f.floods[key]
+	if !ok {
+		s = &e
+		s.RecordID = 0
+		s.FirstOccurrence = e.TimeCreated
+		f.floods[key] = s
+		f.order = append(f.order, key)
+	}
+	s.Occurrences++
+	if e.TimeCreated.Before(s.FirstOccurrence) {
+		s.FirstOccurrence = e.TimeCreated
+	}
+	if e.TimeCreated.After(s.TimeCreated) {
+		s.TimeCreated = e.TimeCreated
+	}
+}
+
+func (f *floodSet) Summaries() []EventInfo {
+	result := make([]EventInfo, 0, len(f.order))
+	for _, key := range f.order {
+		s := *f.floods[key]
+		s.Message = fmt.Sprintf("%d occurrences between %s and %s: %s",
+			s.Occurrences, s.FirstOccurrence.Format(time.RFC3339), s.TimeCreated.Format(time.RFC3339), s.Message)
+		result = append(result, s)
+	}
+	return result
+}
@@ -0,0 +1,290 @@
+package eventlog
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fakeSource serves the events of a log with record IDs. Its bookmark is
+// the record ID of the last event read.
+type fakeSource struct {
+	log []EventInfo
+}
+
+func (s *fakeSource) name() string {
+	return "fake"
+}
+
+func (s *fakeSource) read(bookmark string) ([]EventInfo, string, error) {
+	last, _ := strconv.ParseUint(bookmark, 10, 64)
+	var events []EventInfo
+	for _, e := range s.log {
+		if e.RecordID > last {
+			events = append(events, e)
+			last = e.RecordID
+		}
+	}
+	if last == 0 {
+		return events, bookmark, nil
+	}
+	return events, strconv.FormatUint(last, 10), nil
+}
+
+func (s *fakeSource) append(n int) {
+	for i := 0; i < n; i++ {
+		id := uint64(len(s.log) + 1)
+		s.log = append(s.log, EventInfo{LogName: "fake", EventID: 1, RecordID: id, Message: "event " + strconv.FormatUint(id, 10)})
+	}
+}
+
+// newTestCollector creates a collector over src, as a fresh run of the
+// agent with its bookmarks in path would.
+func newTestCollector(path string, src source) *Collector {
+	return &Collector{
+		enabled:   true,
+		sources:   []source{src},
+		bookmarks: newBookmarkStore(path),
+		positions: make(map[string]string),
+		dedup:     newDedup(dedupSize),
+		limiter:   newLimiter(0),
+	}
+}
+
+func recordIDs(events []EventInfo) []uint64 {
+	ids := make([]uint64, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.RecordID)
+	}
+	return ids
+}
+
+func TestCollectorRestart(t *testing.T) {
+	tests := []struct {
+		name   string
+		commit bool
+		want   int // events collected again by the next run
+	}{
+		{name: "delivered events are not sent again", commit: true, want: 0},
+		{name: "undelivered events are read again", commit: false, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "bookmarks.json")
+			src := &fakeSource{}
+			src.append(3)
+
+			c := newTestCollector(path, src)
+			events, cp, err := c.Collect()
+			if err != nil || len(events) != 3 {
+				t.Fatalf("Collect() = %v, %v; want 3 events", recordIDs(events), err)
+			}
+			if tt.commit {
+				if err := c.Commit(cp); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			restarted := newTestCollector(path, src)
+			events, _, err = restarted.Collect()
+			if err != nil || len(events) != tt.want {
+				t.Errorf("Collect() after restart = %v, %v; want %d events", recordIDs(events), err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectorCheckpoints(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+	src := &fakeSource{}
+	c := newTestCollector(path, src)
+
+	src.append(2)
+	_, first, _ := c.Collect()
+	src.append(2)
+	_, second, _ := c.Collect()
+
+	// The second delivery succeeded but the first did not: nothing may be
+	// persisted past the first batch.
+	if err := c.Commit(0); err != nil {
+		t.Fatal(err)
+	}
+	if got := newBookmarkStore(path).Get("fake"); got != "" {
+		t.Fatalf("bookmark = %q before anything was delivered", got)
+	}
+
+	// A cycle without events does not move the bookmark past undelivered
+	// events either.
+	if events, _, _ := c.Collect(); len(events) != 0 {
+		t.Fatalf("Collect() = %v, want no new events", recordIDs(events))
+	}
+	if got := newBookmarkStore(path).Get("fake"); got != "" {
+		t.Fatalf("bookmark = %q after an empty cycle", got)
+	}
+
+	if err := c.Commit(first); err != nil {
+		t.Fatal(err)
+	}
+	if got := newBookmarkStore(path).Get("fake"); got != "2" {
+		t.Fatalf("bookmark = %q after the first delivery, want 2", got)
+	}
+	if err := c.Commit(second); err != nil {
+		t.Fatal(err)
+	}
+	if got := newBookmarkStore(path).Get("fake"); got != "4" {
+		t.Fatalf("bookmark = %q after the second delivery, want 4", got)
+	}
+}
+
+func TestCollectorSourceError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+	good := &fakeSource{}
+	good.append(2)
+	bad := &failingSource{err: errors.New("journalctl failed")}
+
+	c := newTestCollector(path, good)
+	c.sources = append(c.sources, bad)
+	events, cp, err := c.Collect()
+	if len(events) != 2 || !errors.Is(err, bad.err) {
+		t.Fatalf("Collect() = %v, %v; want 2 events and the source error", recordIDs(events), err)
+	}
+	if err := c.Commit(cp); err != nil {
+		t.Fatal(err)
+	}
+	if got := newBookmarkStore(path).Get("fake"); got != "2" {
+		t.Errorf("bookmark = %q, want 2", got)
+	}
+}
+
+type failingSource struct {
+	err error
+}
+
+func (s *failingSource) name() string {
+	return "failing"
+}
+
+func (s *failingSource) read(bookmark string) ([]EventInfo, string, error) {
+	return nil, bookmark, s.err
+}
+
+func TestDedup(t *testing.T) {
+	d := newDedup(2)
+	a := EventInfo{LogName: "System", RecordID: 1}
+	b := EventInfo{LogName: "System", RecordID: 2}
+	c := EventInfo{LogName: "System", RecordID: 3}
+	noID := EventInfo{LogName: "journal", Source: "kernel", Message: "oops", TimeCreated: time.Unix(100, 0)}
+
+	steps := []struct {
+		event EventInfo
+		seen  bool
+	}{
+		{a, false},
+		{a, true},
+		{EventInfo{LogName: "Application", RecordID: 1}, false},
+		{b, false},
+		// a fell out of the window of two.
+		{a, false},
+		{c, false},
+		{noID, false},
+		{noID, true},
+	}
+	for i, step := range steps {
+		if got := d.Seen(step.event); got != step.seen {
+			t.Errorf("step %d: Seen(%+v) = %v, want %v", i, step.event, got, step.seen)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter(2)
+	e := EventInfo{LogName: "System", Source: "disk", EventID: 7}
+	other := EventInfo{LogName: "System", Source: "disk", EventID: 51}
+	minute := time.Date(2024, 10, 19, 10, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		event EventInfo
+		at    time.Duration
+		allow bool
+	}{
+		{e, 0, true},
+		{e, 10 * time.Second, true},
+		{e, 59*time.Second + 999*time.Millisecond, false},
+		// Other event IDs have their own budget.
+		{other, 30 * time.Second, true},
+		// The budget starts over at the minute boundary.
+		{e, time.Minute, true},
+		{e, time.Minute + time.Second, true},
+		{e, time.Minute + 2*time.Second, false},
+	}
+	for i, step := range steps {
+		if got := l.Allow(step.event, minute.Add(step.at)); got != step.allow {
+			t.Errorf("step %d: Allow(%d at +%v) = %v, want %v", i, step.event.EventID, step.at, got, step.allow)
+		}
+	}
+
+	unlimited := newLimiter(0)
+	for i := 0; i < 100; i++ {
+		if !unlimited.Allow(e, minute) {
+			t.Fatal("a zero limit held back an event")
+		}
+	}
+}
+
+func TestFloodSummaries(t *testing.T) {
+	start := time.Date(2024, 10, 19, 10, 0, 0, 0, time.UTC)
+	disk := EventInfo{LogName: "System", Source: "disk", EventID: 7, Message: "bad block"}
+	ntfs := EventInfo{LogName: "System", Source: "ntfs", EventID: 55, Message: "corrupt"}
+
+	f := newFloodSet()
+	for _, at := range []time.Duration{20 * time.Second, 0, 40 * time.Second} {
+		e := disk
+		e.TimeCreated = start.Add(at)
+		e.RecordID = uint64(at / time.Second)
+		f.Add(e)
+	}
+	e := ntfs
+	e.TimeCreated = start
+	f.Add(e)
+
+	want := []struct {
+		message     string
+		occurrences int
+		first, last time.Time
+	}{
+		{"3 occurrences between 2024-10-19T10:00:00Z and 2024-10-19T10:00:40Z: bad block", 3, start, start.Add(40 * time.Second)},
+		{"1 occurrences between 2024-10-19T10:00:00Z and 2024-10-19T10:00:00Z: corrupt", 1, start, start},
+	}
+	got := f.Summaries()
+	if len(got) != len(want) {
+		t.Fatalf("Summaries() returned %d events, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		s := got[i]
+		if s.Message != w.message || s.Occurrences != w.occurrences || !s.FirstOccurrence.Equal(w.first) || !s.TimeCreated.Equal(w.last) || s.RecordID != 0 {
+			t.Errorf("summary %d = %+v, want %q (%d, %v to %v)", i, s, w.message, w.occurrences, w.first, w.last)
+		}
+	}
+}
+
+func TestProcessFlood(t *testing.T) {
+	c := newTestCollector(filepath.Join(t.TempDir(), "bookmarks.json"), &fakeSource{})
+	c.limiter = newLimiter(2)
+	now := time.Date(2024, 10, 19, 10, 0, 0, 0, time.UTC)
+
+	var events []EventInfo
+	for i := 1; i <= 5; i++ {
+		events = append(events, EventInfo{LogName: "System", Source: "disk", EventID: 7, RecordID: uint64(i), TimeCreated: now, Message: "bad block"})
+	}
+	events = append(events, events[0])
+
+	got := c.process(events, now)
+	if len(got) != 3 {
+		t.Fatalf("process() = %d events, want 2 and a summary", len(got))
+	}
+	if summary := got[2]; summary.Occurrences != 3 {
+		t.Errorf("summary of %d occurrences, want 3 (the duplicate is dropped)", summary.Occurrences)
+	}
+}
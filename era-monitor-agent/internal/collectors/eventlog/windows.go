@@ -7,83 +7,116 @@ import (
 	"encoding/json"
 	"fmt"
 	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 )
 
-// EventInfo represents a single event log entry
-type EventInfo struct {
-	LogName     string    `json:"logName"`
-	EventID     int       `json:"eventId"`
-	Level       string    `json:"level"`
-	Source      string    `json:"source"`
-	Message     string    `json:"message"`
-	TimeCreated time.Time `json:"timeCreated"`
-	Category    string    `json:"category"`
-}
-
 // PowerShell event structure
 type psEvent struct {
 	LogName     string `json:"LogName"`
 	Id          int    `json:"Id"`
+	RecordId    uint64 `json:"RecordId"`
 	LevelText   string `json:"LevelDisplayName"`
 	Source      string `json:"ProviderName"`
 	Message     string `json:"Message"`
 	TimeCreated string `json:"TimeCreated"`
 }
 
-// Collector collects Windows Event Logs
-type Collector struct {
-	enabled bool
+// criticalEvents are the Windows event log sources, one per log and
+// category.
+var criticalEvents = []struct {
+	log      string
+	category string
+	eventIDs []int
+}{
+	{"System", "System", []int{6008, 41, 1074, 7023, 7024, 7031}},
+	{"System", "Disk", []int{7, 11, 51, 52, 153, 154, 129}},
+	{"Application", "SQL", []int{17063, 17065, 9002, 823, 824, 825}},
+	{"Application", "System", []int{1000, 1001, 1002}},
+	{"Security", "Security", []int{4625, 4740, 4720}},
 }
 
-// NewCollector creates a new event log collector
-func NewCollector(enabled bool) *Collector {
-	return &Collector{
-		enabled: enabled,
+const (
+	// initialEvents is how many of the newest events are read from a source
+	// without a bookmark.
+	initialEvents = 100
+	// batchEvents is how many events are read per source and cycle after
+	// that. A larger burst is read over the following cycles.
+	batchEvents = 1000
+)
+
+func platformSources() []source {
+	sources := make([]source, len(criticalEvents))
+	for i, e := range criticalEvents {
+		sources[i] = &windowsSource{log: e.log, category: e.category, eventIDs: e.eventIDs}
 	}
+	return sources
 }
 
-// Collect retrieves critical event logs from Windows
-func (c *Collector) Collect() ([]EventInfo, error) {
-	if !c.enabled {
-		return nil, nil
-	}
+// windowsSource reads selected event IDs of one Windows event log. Its
+// bookmark is the time and record ID of the last event read, as
+// "2006-01-02T15:04:05.0000000Z#1234".
+type windowsSource struct {
+	log      string
+	category string
+	eventIDs []int
+}
+
+func (s *windowsSource) name() string {
+	return "windows:" + s.log + ":" + s.category
+}
 
-	var allEvents []EventInfo
+func (s *windowsSource) read(bookmark string) ([]EventInfo, string, error) {
+	since, lastRecord, ok := parseWindowsBookmark(bookmark)
 
-	// Define critical event sources
-	criticalEvents := []struct {
-		log      string
-		category string
-		eventIDs []int
-	}{
-		{"System", "System", []int{6008, 41, 1074, 7023, 7024, 7031}},
-		{"System", "Disk", []int{7, 11, 51, 52, 153, 154, 129}},
-		{"Application", "SQL", []int{17063, 17065, 9002, 823, 824, 825}},
-		{"Application", "System", []int{1000, 1001, 1002}},
-		{"Security", "Security", []int{4625, 4740, 4720}},
+	var events []EventInfo
+	var err error
+	if ok {
+		events, err = queryEvents(s.log, s.category, s.eventIDs, since, batchEvents)
+	} else {
+		events, err = queryEvents(s.log, s.category, s.eventIDs, "", initialEvents)
+	}
+	if err != nil {
+		return nil, bookmark, err
 	}
 
-	// Collect from each source
-	for _, source := range criticalEvents {
-		events, err := c.queryEvents(source.log, source.category, source.eventIDs, 100)
-		if err != nil {
-			continue // Skip errors, continue with other sources
+	// The start time is inclusive, so the last event read before comes
+	// back; record IDs only grow within a log.
+	var result []EventInfo
+	for _, e := range events {
+		if ok && e.RecordID <= lastRecord {
+			continue
 		}
-		allEvents = append(allEvents, events...)
+		result = append(result, e)
 	}
 
-	// Limit total events
-	if len(allEvents) > 1000 {
-		allEvents = allEvents[:1000]
+	next := bookmark
+	for _, e := range result {
+		if e.RecordID > lastRecord {
+			lastRecord = e.RecordID
+			next = e.TimeCreated.UTC().Format("2006-01-02T15:04:05.0000000Z") + "#" + strconv.FormatUint(e.RecordID, 10)
+		}
 	}
+	return result, next, nil
+}
 
-	return allEvents, nil
+func parseWindowsBookmark(bookmark string) (string, uint64, bool) {
+	since, record, ok := strings.Cut(bookmark, "#")
+	if !ok {
+		return "", 0, false
+	}
+	id, err := strconv.ParseUint(record, 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return since, id, true
 }
 
-func (c *Collector) queryEvents(channelPath, category string, eventIDs []int, maxEvents int) ([]EventInfo, error) {
+// queryEvents reads events of a log. With a start time the oldest events
+// from then on are returned, otherwise the newest ones.
+func queryEvents(channelPath, category string, eventIDs []int, since string, maxEvents int) ([]EventInfo, error) {
 	// Build PowerShell command to get events
 	eventIDFilter := ""
 	for i, id := range eventIDs {
@@ -93,30 +126,39 @@ func (c *Collector) queryEvents(channelPath, category string, eventIDs []int, ma
 		eventIDFilter += strconv.Itoa(id)
 	}
 
+	startTime := ""
+	oldest := ""
+	if since != "" {
+		startTime = fmt.Sprintf("StartTime=[datetime]'%s'", since)
+		oldest = "-Oldest"
+	}
+
 	// PowerShell command to get events as JSON
 	psCmd := fmt.Sprintf(`
 		[Console]::OutputEncoding = [System.Text.Encoding]::UTF8
 		$events = Get-WinEvent -FilterHashtable @{
 			LogName='%s'
 			ID=%s
-		} -MaxEvents %d -ErrorAction SilentlyContinue | Select-Object -First %d
+			%s
+		} -MaxEvents %d %s -ErrorAction SilentlyContinue
 		
 		$events | ForEach-Object {
 			[PSCustomObject]@{
 				LogName = $_.LogName
 				Id = $_.Id
+				RecordId = $_.RecordId
 				LevelDisplayName = $_.LevelDisplayName
 				ProviderName = $_.ProviderName
 				Message = $_.Message
-				TimeCreated = $_.TimeCreated.ToString('o')
+				TimeCreated = $_.TimeCreated.ToUniversalTime().ToString('o')
 			}
 		} | ConvertTo-Json -Depth 2
-	`, channelPath, eventIDFilter, maxEvents, maxEvents)
+	`, channelPath, eventIDFilter, startTime, maxEvents, oldest)
 
 	// Execute PowerShell
 	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", psCmd)
 	output, err := cmd.Output()
-	if err != nil {
+	if err != nil || len(strings.TrimSpace(string(output))) == 0 {
 		// No events found is not an error
 		return []EventInfo{}, nil
 	}
@@ -146,10 +188,14 @@ func (c *Collector) queryEvents(channelPath, category string, eventIDs []int, ma
 			Message:     pse.Message,
 			TimeCreated: timeCreated,
 			Category:    category,
+			RecordID:    pse.RecordId,
 		})
 	}
 
-	fmt.Printf("Collected %d events from %s\n", len(events), channelPath)
+	// Newest-first results are put in chronological order
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].RecordID < events[j].RecordID
+	})
 	return events, nil
 }
 
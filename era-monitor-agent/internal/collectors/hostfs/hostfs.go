@@ -0,0 +1,104 @@
+// Package hostfs resolves host paths for an agent that runs in a container
+// with the host's /proc, /sys, /etc and root filesystem mounted inside it.
+// It follows the HOST_PROC, HOST_SYS, HOST_ETC and HOST_ROOT variables that
+// gopsutil honours as well, so both see the same host.
+package hostfs
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/eracloud/era-monitor-agent/internal/config"
+)
+
+// View modes reported with the system metrics.
+const (
+	// ViewHost means the agent runs directly on the host.
+	ViewHost = "host"
+	// ViewContainer means the agent runs in a container and only sees the
+	// container.
+	ViewContainer = "container"
+	// ViewHostMounts means the agent runs in a container and reads the host
+	// through mounted host paths.
+	ViewHostMounts = "host-mounts"
+)
+
+// Configure applies the host-mode settings. Variables already present in the
+// environment win over the configured paths, and paths left empty default to
+// the usual /host mounts. It changes the process environment, so it is called
+// once at startup, before any collector is built.
+func Configure(cfg config.HostModeConfig) {
+	if !cfg.Enabled {
+		return
+	}
+	setDefault("HOST_PROC", cfg.Proc, "/host/proc")
+	setDefault("HOST_SYS", cfg.Sys, "/host/sys")
+	setDefault("HOST_ETC", cfg.Etc, "/host/etc")
+	setDefault("HOST_ROOT", cfg.Root, "/host")
+}
+
+func setDefault(key, value, fallback string) {
+	if os.Getenv(key) != "" {
+		return
+	}
+	if value == "" {
+		value = fallback
+	}
+	os.Setenv(key, value)
+}
+
+// Enabled reports whether host paths are redirected.
+func Enabled() bool {
+	return os.Getenv("HOST_PROC") != "" || os.Getenv("HOST_ROOT") != ""
+}
+
+// Proc returns a path below the host's /proc.
+func Proc(parts ...string) string {
+	return join("HOST_PROC", "/proc", parts)
+}
+
+// Sys returns a path below the host's /sys.
+func Sys(parts ...string) string {
+	return join("HOST_SYS", "/sys", parts)
+}
+
+// Etc returns a path below the host's /etc.
+func Etc(parts ...string) string {
+	return join("HOST_ETC", "/etc", parts)
+}
+
+// Root returns an absolute host path as seen from the agent, e.g. a
+// mountpoint to stat.
+func Root(path string) string {
+	root := os.Getenv("HOST_ROOT")
+	if root == "" || root == "/" {
+		return path
+	}
+	return filepath.Join(root, path)
+}
+
+// NetProc returns a file below /proc/net of the host's network namespace.
+// /proc/net follows the reading process, so in host mode the files of PID 1
+// are used instead.
+func NetProc(name string) string {
+	if os.Getenv("HOST_PROC") != "" {
+		return Proc("1", "net", name)
+	}
+	return Proc("net", name)
+}
+
+func join(key, fallback string, parts []string) string {
+	base := os.Getenv(key)
+	if base == "" {
+		base = fallback
+	}
+	return filepath.Join(append([]string{base}, parts...)...)
+}
+
+// CollectionView describes which view of the system the metrics reflect.
+// The namespace fields are "host", "container" or "unknown".
+type CollectionView struct {
+	Mode         string
+	PIDNamespace string
+	NetNamespace string
+}
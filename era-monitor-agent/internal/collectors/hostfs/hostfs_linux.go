@@ -0,0 +1,72 @@
+//go:build linux
+
+package hostfs
+
+import (
+	"os"
+	"strings"
+)
+
+// InContainer reports whether the agent runs inside a container.
+func InContainer() bool {
+	if exists("/.dockerenv") || exists("/run/.containerenv") {
+		return true
+	}
+	if os.Getenv("container") != "" {
+		return true
+	}
+	data, err := os.ReadFile("/proc/1/cgroup")
+	if err != nil {
+		return false
+	}
+	for _, marker := range []string{"docker", "kubepods", "containerd", "libpod", "lxc"} {
+		if strings.Contains(string(data), marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// View reports the view the collectors get. Namespaces are compared with
+// those of the host's PID 1, which needs CAP_SYS_PTRACE inside a container;
+// without it they are "unknown".
+func View() CollectionView {
+	if !InContainer() {
+		return CollectionView{Mode: ViewHost, PIDNamespace: "host", NetNamespace: "host"}
+	}
+
+	v := CollectionView{Mode: ViewContainer, PIDNamespace: "container", NetNamespace: "container"}
+	if Enabled() {
+		v.Mode = ViewHostMounts
+		v.PIDNamespace = namespace("pid")
+		v.NetNamespace = namespace("net")
+	}
+	return v
+}
+
+// SharesNetNamespace reports whether the agent runs in the host's network
+// namespace, e.g. with hostNetwork or network_mode: host. It assumes so
+// outside host mode and when the namespaces cannot be compared.
+func SharesNetNamespace() bool {
+	return !Enabled() || namespace("net") != "container"
+}
+
+func namespace(ns string) string {
+	self, err := os.Readlink("/proc/self/ns/" + ns)
+	if err != nil {
+		return "unknown"
+	}
+	host, err := os.Readlink(Proc("1", "ns", ns))
+	if err != nil {
+		return "unknown"
+	}
+	if self == host {
+		return "host"
+	}
+	return "container"
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package hostfs
+
+// InContainer always reports false; container detection is Linux specific.
+func InContainer() bool {
+	return false
+}
+
+// View always reports the host view on this platform.
+func View() CollectionView {
+	return CollectionView{Mode: ViewHost, PIDNamespace: "host", NetNamespace: "host"}
+}
+
+// SharesNetNamespace always reports true on this platform.
+func SharesNetNamespace() bool {
+	return true
+}
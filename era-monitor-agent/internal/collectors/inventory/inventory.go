@@ -0,0 +1,136 @@
+// Package inventory describes the hardware and operating system of the host
+// for asset management: firmware identification, CPU, memory, disks and
+// network adapters.
+package inventory
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+	"github.com/eracloud/era-monitor-agent/internal/config"
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+const bytesPerMB = 1024 * 1024
+
+// Collector reads the inventory once per refresh interval. The result is
+// cached in between, since hardware rarely changes.
+type Collector struct {
+	refresh time.Duration
+	// interfaceExclude holds glob patterns of network interfaces left out
+	// of the inventory.
+	interfaceExclude []string
+
+	mu          sync.Mutex
+	info        *api.HostInventory
+	collectedAt time.Time
+}
+
+// NewCollector creates an inventory collector
+func NewCollector(cfg config.InventoryConfig, interfaceExclude []string) *Collector {
+	refresh := time.Duration(cfg.RefreshMinutes) * time.Minute
+	if refresh <= 0 {
+		refresh = 15 * time.Minute
+	}
+	return &Collector{refresh: refresh, interfaceExclude: interfaceExclude}
+}
+
+// Collect returns the current inventory. Its Hash identifies the content, so
+// callers can tell whether it changed since it was last sent.
+func (c *Collector) Collect(ctx context.Context) *api.HostInventory {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.info != nil && time.Since(c.collectedAt) < c.refresh {
+		return c.info
+	}
+
+	c.info = c.collect(ctx)
+	c.collectedAt = time.Now()
+	return c.info
+}
+
+func (c *Collector) collect(ctx context.Context) *api.HostInventory {
+	inv := &api.HostInventory{
+		OS:   runtime.GOOS,
+		Arch: runtime.GOARCH,
+	}
+
+	if h, err := host.InfoWithContext(ctx); err == nil {
+		inv.Platform = h.Platform
+		inv.PlatformVersion = h.PlatformVersion
+		inv.KernelVersion = h.KernelVersion
+		if h.KernelArch != "" {
+			inv.Arch = h.KernelArch
+		}
+		inv.BootTime = time.Unix(int64(h.BootTime), 0).UTC()
+		inv.Virtualization = h.VirtualizationSystem
+		inv.VirtualizationRole = h.VirtualizationRole
+	}
+
+	if infos, err := cpu.InfoWithContext(ctx); err == nil && len(infos) > 0 {
+		inv.CPUModel = infos[0].ModelName
+		sockets := make(map[string]bool)
+		for _, info := range infos {
+			if info.PhysicalID != "" {
+				sockets[info.PhysicalID] = true
+			}
+		}
+		inv.CPUSockets = len(sockets)
+	}
+	inv.CPUCores, _ = cpu.CountsWithContext(ctx, false)
+	inv.CPUThreads, _ = cpu.CountsWithContext(ctx, true)
+
+	if v, err := mem.VirtualMemoryWithContext(ctx); err == nil {
+		inv.MemoryTotalMB = v.Total / bytesPerMB
+	}
+
+	inv.Container = containerRuntime()
+	inv.DMI = readDMI()
+	inv.BlockDevices = blockDevices()
+	for _, nic := range nics(ctx) {
+		if !c.interfaceExcluded(nic.Name) {
+			inv.NICs = append(inv.NICs, nic)
+		}
+	}
+
+	inv.Hash = hash(inv)
+	return inv
+}
+
+func (c *Collector) interfaceExcluded(name string) bool {
+	for _, pattern := range c.interfaceExclude {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hash identifies the inventory content. Boot time is rounded to the minute
+// since it is derived from uptime and jitters by a second between reads.
+// Virtual NICs are left out: container engines create and remove bridges
+// and veth pairs all the time, which must not resend the inventory.
+func hash(inv *api.HostInventory) string {
+	stable := *inv
+	stable.Hash = ""
+	stable.BootTime = stable.BootTime.Truncate(time.Minute)
+	stable.NICs = nil
+	for _, nic := range inv.NICs {
+		if !nic.Virtual {
+			stable.NICs = append(stable.NICs, nic)
+		}
+	}
+	data, _ := json.Marshal(stable)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
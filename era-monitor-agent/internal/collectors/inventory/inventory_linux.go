@@ -0,0 +1,147 @@
+//go:build linux
+
+package inventory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+	"github.com/eracloud/era-monitor-agent/internal/collectors/hostfs"
+)
+
+// ignoredBlockDevices are virtual devices that are not part of the hardware.
+var ignoredBlockDevices = []string{"loop*", "ram*", "zram*"}
+
+func readDMI() *api.DMIInfo {
+	dmi := func(name string) string {
+		return readString(hostfs.Sys("class/dmi/id", name))
+	}
+
+	// product_serial and product_uuid are only readable by root.
+	info := &api.DMIInfo{
+		SystemVendor:  dmi("sys_vendor"),
+		ProductName:   dmi("product_name"),
+		ProductSerial: dmi("product_serial"),
+		ProductUUID:   dmi("product_uuid"),
+		BoardVendor:   dmi("board_vendor"),
+		BoardName:     dmi("board_name"),
+		BIOSVendor:    dmi("bios_vendor"),
+		BIOSVersion:   dmi("bios_version"),
+		BIOSDate:      dmi("bios_date"),
+	}
+	if *info == (api.DMIInfo{}) {
+		return nil
+	}
+	return info
+}
+
+func blockDevices() []api.BlockDeviceInfo {
+	entries, err := os.ReadDir(hostfs.Sys("block"))
+	if err != nil {
+		return nil
+	}
+
+	var devices []api.BlockDeviceInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		if ignored(name) {
+			continue
+		}
+		dir := hostfs.Sys("block", name)
+
+		// size is always counted in 512 byte sectors.
+		sectors, _ := strconv.ParseUint(readString(filepath.Join(dir, "size")), 10, 64)
+		if sectors == 0 {
+			continue
+		}
+
+		devices = append(devices, api.BlockDeviceInfo{
+			Name:       name,
+			SizeBytes:  sectors * 512,
+			Model:      readString(filepath.Join(dir, "device", "model")),
+			Vendor:     readString(filepath.Join(dir, "device", "vendor")),
+			Serial:     readString(filepath.Join(dir, "device", "serial")),
+			Rotational: readString(filepath.Join(dir, "queue", "rotational")) == "1",
+			Removable:  readString(filepath.Join(dir, "removable")) == "1",
+		})
+	}
+	return devices
+}
+
+func nics(ctx context.Context) []api.NICInfo {
+	entries, err := os.ReadDir(hostfs.Sys("class/net"))
+	if err != nil {
+		return nil
+	}
+
+	var result []api.NICInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == "lo" {
+			continue
+		}
+		dir := hostfs.Sys("class/net", name)
+
+		nic := api.NICInfo{
+			Name: name,
+			MAC:  readString(filepath.Join(dir, "address")),
+		}
+		// speed reads as -1 or fails while the link is down.
+		if speed, err := strconv.Atoi(readString(filepath.Join(dir, "speed"))); err == nil && speed > 0 {
+			nic.SpeedMbps = speed
+		}
+		if driver, err := os.Readlink(filepath.Join(dir, "device", "driver")); err == nil {
+			nic.Driver = filepath.Base(driver)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "device")); err != nil {
+			nic.Virtual = true
+		}
+		result = append(result, nic)
+	}
+	return result
+}
+
+// containerRuntime names the container engine the agent runs in. It is empty
+// on the host, and in host mode, where the inventory describes the host.
+func containerRuntime() string {
+	if hostfs.Enabled() {
+		return ""
+	}
+	switch {
+	case os.Getenv("KUBERNETES_SERVICE_HOST") != "":
+		return "kubernetes"
+	case fileExists("/.dockerenv"):
+		return "docker"
+	case fileExists("/run/.containerenv"):
+		return "podman"
+	case hostfs.InContainer():
+		return "container"
+	}
+	return ""
+}
+
+func ignored(name string) bool {
+	for _, pattern := range ignoredBlockDevices {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func readString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,41 @@
+//go:build !linux
+
+package inventory
+
+import (
+	"context"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// readDMI is not supported on this platform.
+func readDMI() *api.DMIInfo {
+	return nil
+}
+
+// blockDevices is not supported on this platform.
+func blockDevices() []api.BlockDeviceInfo {
+	return nil
+}
+
+func nics(ctx context.Context) []api.NICInfo {
+	ifaces, err := net.InterfacesWithContext(ctx)
+	if err != nil {
+		return nil
+	}
+
+	var result []api.NICInfo
+	for _, iface := range ifaces {
+		if iface.HardwareAddr == "" {
+			continue
+		}
+		result = append(result, api.NICInfo{Name: iface.Name, MAC: iface.HardwareAddr})
+	}
+	return result
+}
+
+// containerRuntime is not detected on this platform.
+func containerRuntime() string {
+	return ""
+}
@@ -0,0 +1,67 @@
+package packages
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+	"github.com/eracloud/era-monitor-agent/internal/collectors/hostfs"
+)
+
+const dpkgStatusPath = "/var/lib/dpkg/status"
+
+func readDpkg(ctx context.Context) ([]api.PackageInfo, error) {
+	f, err := os.Open(hostfs.Root(dpkgStatusPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseDpkgStatus(f)
+}
+
+// ParseDpkgStatus parses a dpkg status file, a list of blank line separated
+// paragraphs with one package each. Only installed packages are returned;
+// removed packages whose configuration files remain are skipped.
+func ParseDpkgStatus(r io.Reader) ([]api.PackageInfo, error) {
+	var result []api.PackageInfo
+	fields := make(map[string]string)
+
+	flush := func() {
+		if fields["Package"] != "" && strings.HasSuffix(fields["Status"], " installed") {
+			result = append(result, api.PackageInfo{
+				Name:    fields["Package"],
+				Version: fields["Version"],
+				Arch:    fields["Architecture"],
+				Manager: ManagerDpkg,
+			})
+		}
+		fields = make(map[string]string)
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			flush()
+			continue
+		}
+		// Continuation lines of multi-line fields start with whitespace.
+		if line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok {
+			fields[name] = strings.TrimSpace(value)
+		}
+	}
+	flush()
+
+	return result, scanner.Err()
+}
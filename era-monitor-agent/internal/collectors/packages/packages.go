@@ -0,0 +1,185 @@
+// Package packages lists the software packages installed through the
+// system package managers, dpkg and rpm. The first report carries the full
+// list; after that only packages that were installed, upgraded or removed
+// are sent.
+package packages
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+	"github.com/eracloud/era-monitor-agent/internal/config"
+)
+
+// Package managers reported in api.PackageInfo.Manager.
+const (
+	ManagerDpkg = "dpkg"
+	ManagerRPM  = "rpm"
+)
+
+// Collector re-reads the package databases once per refresh interval and
+// diffs the result against the list the server last accepted.
+type Collector struct {
+	refresh time.Duration
+	read    func(context.Context) ([]api.PackageInfo, error)
+
+	mu          sync.Mutex
+	current     map[string]api.PackageInfo
+	currentHash string
+	readAt      time.Time
+
+	// baseline is the list the server has, nil until the first full list
+	// was accepted.
+	baseline     map[string]api.PackageInfo
+	baselineHash string
+	forceFull    bool
+}
+
+// NewCollector creates a package inventory collector
+func NewCollector(cfg config.PackagesConfig) *Collector {
+	refresh := time.Duration(cfg.RefreshMinutes) * time.Minute
+	if refresh <= 0 {
+		refresh = time.Hour
+	}
+	return &Collector{refresh: refresh, read: read}
+}
+
+// Prepare returns the packages to report: the full list when the server has
+// none yet, otherwise the changes since the accepted list. It returns nil
+// when nothing changed. Commit must be called once the server accepted it.
+func (c *Collector) Prepare(ctx context.Context) *api.PackageInventory {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.readAt.IsZero() || time.Since(c.readAt) >= c.refresh {
+		// A database that fails to read keeps the previous list until the
+		// next refresh, rather than reporting its packages as removed.
+		pkgs, err := c.read(ctx)
+		if err == nil {
+			c.current = index(pkgs)
+			c.currentHash = hash(c.current)
+		}
+		c.readAt = time.Now()
+	}
+	// Without any package database there is nothing to report.
+	if len(c.current) == 0 {
+		return nil
+	}
+
+	inv := &api.PackageInventory{
+		Hash:  c.currentHash,
+		Count: len(c.current),
+	}
+
+	if c.baseline == nil || c.forceFull {
+		inv.Mode = api.HeartbeatModeFull
+		inv.Packages = sorted(c.current)
+		return inv
+	}
+	if c.currentHash == c.baselineHash {
+		return nil
+	}
+
+	inv.Mode = api.HeartbeatModeDelta
+	for key, pkg := range c.current {
+		if prev, ok := c.baseline[key]; !ok || prev.Version != pkg.Version {
+			inv.Packages = append(inv.Packages, pkg)
+		}
+	}
+	for key, pkg := range c.baseline {
+		if _, ok := c.current[key]; !ok {
+			inv.Removed = append(inv.Removed, pkg)
+		}
+	}
+	sortPackages(inv.Packages)
+	sortPackages(inv.Removed)
+	return inv
+}
+
+// Commit records that the server accepted inv. A list that was re-read in
+// the meantime is not committed; the next Prepare then diffs against the
+// older baseline, which is still correct.
+func (c *Collector) Commit(inv *api.PackageInventory) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if inv.Hash != c.currentHash {
+		return
+	}
+	c.baseline = c.current
+	c.baselineHash = c.currentHash
+	if inv.Mode == api.HeartbeatModeFull {
+		c.forceFull = false
+	}
+}
+
+// RequestFull makes the next Prepare return the full list.
+func (c *Collector) RequestFull() {
+	c.mu.Lock()
+	c.forceFull = true
+	c.mu.Unlock()
+}
+
+// read lists the packages of every package manager present on the host.
+// Errors of one manager do not hide the packages of another.
+func read(ctx context.Context) ([]api.PackageInfo, error) {
+	var result []api.PackageInfo
+	var firstErr error
+	for _, source := range []func(context.Context) ([]api.PackageInfo, error){readDpkg, readRPM} {
+		pkgs, err := source(ctx)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		result = append(result, pkgs...)
+	}
+	return result, firstErr
+}
+
+// key identifies a package in the diff. rpm installs several versions of
+// installonly packages such as the kernel side by side, so for rpm the
+// version is part of the identity and an upgrade shows up as the new
+// version installed and the old one removed.
+func key(pkg api.PackageInfo) string {
+	k := pkg.Manager + "/" + pkg.Name + "/" + pkg.Arch
+	if pkg.Manager == ManagerRPM {
+		k += "/" + pkg.Version
+	}
+	return k
+}
+
+func index(pkgs []api.PackageInfo) map[string]api.PackageInfo {
+	m := make(map[string]api.PackageInfo, len(pkgs))
+	for _, pkg := range pkgs {
+		m[key(pkg)] = pkg
+	}
+	return m
+}
+
+func sorted(m map[string]api.PackageInfo) []api.PackageInfo {
+	pkgs := make([]api.PackageInfo, 0, len(m))
+	for _, pkg := range m {
+		pkgs = append(pkgs, pkg)
+	}
+	sortPackages(pkgs)
+	return pkgs
+}
+
+func sortPackages(pkgs []api.PackageInfo) {
+	sort.Slice(pkgs, func(i, j int) bool {
+		return key(pkgs[i]) < key(pkgs[j])
+	})
+}
+
+// hash covers the complete list so the server can detect a lost diff.
+func hash(m map[string]api.PackageInfo) string {
+	h := sha256.New()
+	for _, pkg := range sorted(m) {
+		h.Write([]byte(key(pkg) + "=" + pkg.Version + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
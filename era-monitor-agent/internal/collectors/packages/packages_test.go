@@ -0,0 +1,135 @@
+package packages
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+)
+
+func TestParseDpkgStatus(t *testing.T) {
+	f, err := os.Open("testdata/dpkg-status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	pkgs, err := ParseDpkgStatus(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []api.PackageInfo{
+		{Name: "adduser", Version: "3.134", Arch: "all", Manager: ManagerDpkg},
+		{Name: "libc6", Version: "2.36-9+deb12u4", Arch: "amd64", Manager: ManagerDpkg},
+		{Name: "libc6", Version: "2.36-9+deb12u4", Arch: "i386", Manager: ManagerDpkg},
+		{Name: "tzdata", Version: "2024a-0+deb12u1", Arch: "all", Manager: ManagerDpkg},
+	}
+	if !reflect.DeepEqual(pkgs, want) {
+		t.Errorf("ParseDpkgStatus() =\n%+v\nwant\n%+v", pkgs, want)
+	}
+}
+
+func TestParseRPMQuery(t *testing.T) {
+	f, err := os.Open("testdata/rpm-qa.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	pkgs, err := ParseRPMQuery(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []api.PackageInfo{
+		{Name: "bash", Version: "5.1.8-9.el9", Arch: "x86_64", Manager: ManagerRPM},
+		{Name: "kernel-core", Version: "5.14.0-362.8.1.el9_3", Arch: "x86_64", Manager: ManagerRPM},
+		{Name: "kernel-core", Version: "5.14.0-362.13.1.el9_3", Arch: "x86_64", Manager: ManagerRPM},
+		{Name: "openssl", Version: "1:3.0.7-24.el9", Arch: "x86_64", Manager: ManagerRPM},
+		{Name: "tzdata", Version: "2024a-1.el9", Arch: "noarch", Manager: ManagerRPM},
+	}
+	if !reflect.DeepEqual(pkgs, want) {
+		t.Errorf("ParseRPMQuery() =\n%+v\nwant\n%+v", pkgs, want)
+	}
+}
+
+// Installonly packages keep every installed version, so removing the older
+// kernel is reported as such instead of being lost in the index.
+func TestIndexKeepsRPMVersions(t *testing.T) {
+	f, err := os.Open("testdata/rpm-qa.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	pkgs, err := ParseRPMQuery(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(index(pkgs)); got != len(pkgs) {
+		t.Fatalf("index() kept %d of %d packages", got, len(pkgs))
+	}
+
+	old := api.PackageInfo{Name: "kernel-core", Version: "5.14.0-362.8.1.el9_3", Arch: "x86_64", Manager: ManagerRPM}
+	c := &Collector{refresh: time.Hour, baseline: index(pkgs)}
+	var remaining []api.PackageInfo
+	for _, pkg := range pkgs {
+		if pkg != old {
+			remaining = append(remaining, pkg)
+		}
+	}
+	c.current = index(remaining)
+	c.currentHash = hash(c.current)
+	c.baselineHash = hash(c.baseline)
+	c.readAt = time.Now()
+
+	inv := c.Prepare(t.Context())
+	if inv == nil || inv.Mode != api.HeartbeatModeDelta {
+		t.Fatalf("Prepare() = %+v, want a delta", inv)
+	}
+	if len(inv.Packages) != 0 || !reflect.DeepEqual(inv.Removed, []api.PackageInfo{old}) {
+		t.Errorf("Prepare() packages %+v, removed %+v; want only %+v removed", inv.Packages, inv.Removed, old)
+	}
+}
+
+// A failed read keeps the last list instead of reporting every package as
+// removed, and is not retried before the next refresh.
+func TestPrepareKeepsListOnReadError(t *testing.T) {
+	pkgs := []api.PackageInfo{
+		{Name: "bash", Version: "5.2.15-2", Arch: "amd64", Manager: ManagerDpkg},
+		{Name: "curl", Version: "7.88.1-10", Arch: "amd64", Manager: ManagerDpkg},
+	}
+	reads := 0
+	var readErr error
+	c := &Collector{refresh: time.Hour, read: func(context.Context) ([]api.PackageInfo, error) {
+		reads++
+		if readErr != nil {
+			return pkgs[:1], readErr
+		}
+		return pkgs, nil
+	}}
+
+	inv := c.Prepare(t.Context())
+	if inv == nil || inv.Mode != api.HeartbeatModeFull || inv.Count != 2 {
+		t.Fatalf("Prepare() = %+v, want the full list of 2", inv)
+	}
+	c.Commit(inv)
+
+	readErr = errors.New("rpm: database locked")
+	c.readAt = time.Now().Add(-2 * time.Hour)
+	if inv := c.Prepare(t.Context()); inv != nil {
+		t.Errorf("Prepare() after a failed read = %+v, want no change", inv)
+	}
+	if time.Since(c.readAt) > time.Minute {
+		t.Errorf("readAt was not advanced after a failed read")
+	}
+	c.Prepare(t.Context())
+	if reads != 2 {
+		t.Errorf("package databases read %d times, want 2", reads)
+	}
+}
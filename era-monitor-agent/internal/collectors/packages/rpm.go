@@ -0,0 +1,84 @@
+package packages
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+	"github.com/eracloud/era-monitor-agent/internal/collectors/hostfs"
+)
+
+// rpmQueryFormat prints one tab separated package per line.
+const rpmQueryFormat = `%{NAME}\t%{EPOCH}\t%{VERSION}-%{RELEASE}\t%{ARCH}\n`
+
+const rpmTimeout = 30 * time.Second
+
+// rpmDatabases are the locations of the rpm database, bdb or sqlite.
+var rpmDatabases = []string{"/var/lib/rpm", "/usr/lib/sysimage/rpm"}
+
+func readRPM(ctx context.Context) ([]api.PackageInfo, error) {
+	found := false
+	for _, dir := range rpmDatabases {
+		for _, name := range []string{"rpmdb.sqlite", "Packages"} {
+			if _, err := os.Stat(filepath.Join(hostfs.Root(dir), name)); err == nil {
+				found = true
+			}
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	if _, err := exec.LookPath("rpm"); err != nil {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, rpmTimeout)
+	defer cancel()
+
+	args := []string{"-qa", "--queryformat", rpmQueryFormat}
+	if hostfs.Enabled() {
+		args = append([]string{"--root", hostfs.Root("/")}, args...)
+	}
+	out, err := exec.CommandContext(ctx, "rpm", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("rpm query failed: %w", err)
+	}
+	return ParseRPMQuery(bytes.NewReader(out))
+}
+
+// ParseRPMQuery parses the output of rpm -qa with rpmQueryFormat. Epochs
+// other than none are prefixed to the version, as in "1:2.3-4.el9".
+func ParseRPMQuery(r io.Reader) ([]api.PackageInfo, error) {
+	var result []api.PackageInfo
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 4 || fields[0] == "" {
+			continue
+		}
+		// gpg-pubkey entries are imported signing keys, not software.
+		if fields[0] == "gpg-pubkey" {
+			continue
+		}
+
+		version := fields[2]
+		if epoch := fields[1]; epoch != "" && epoch != "(none)" && epoch != "0" {
+			version = epoch + ":" + version
+		}
+		result = append(result, api.PackageInfo{
+			Name:    fields[0],
+			Version: version,
+			Arch:    fields[3],
+			Manager: ManagerRPM,
+		})
+	}
+	return result, scanner.Err()
+}
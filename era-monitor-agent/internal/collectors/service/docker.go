@@ -7,14 +7,18 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
 	"github.com/eracloud/era-monitor-agent/internal/api"
+	"github.com/eracloud/era-monitor-agent/internal/collectors/cgroup"
 )
 
 type DockerMonitor struct {
 	containers []string
 	client     *client.Client
+
+	// cgroups is set when container cgroup limits and usage are reported.
+	cgroups *cgroup.Reader
 }
 
-func NewDockerMonitor(containers []string) (*DockerMonitor, error) {
+func NewDockerMonitor(containers []string, cgroups bool) (*DockerMonitor, error) {
 	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
 		return nil, err
@@ -27,10 +31,14 @@ func NewDockerMonitor(containers []string) (*DockerMonitor, error) {
 		return nil, err
 	}
 
-	return &DockerMonitor{
+	m := &DockerMonitor{
 		containers: containers,
 		client:     cli,
-	}, nil
+	}
+	if cgroups {
+		m.cgroups = cgroup.NewReader()
+	}
+	return m, nil
 }
 
 func (m *DockerMonitor) Name() string {
@@ -74,7 +82,7 @@ func (m *DockerMonitor) GetServices() ([]api.ServiceInfo, error) {
 			name = container.ID[:12]
 		}
 
-		result = append(result, api.ServiceInfo{
+		svc := api.ServiceInfo{
 			Name:        name,
 			DisplayName: container.Image,
 			Type:        "DockerContainer",
@@ -85,7 +93,13 @@ func (m *DockerMonitor) GetServices() ([]api.ServiceInfo, error) {
 				"status":       container.Status,
 				"ports":        container.Ports,
 			},
-		})
+		}
+		if m.cgroups != nil && container.State == "running" {
+			if path := cgroup.ContainerPath(container.ID); path != "" {
+				svc.Cgroup, _ = m.cgroups.Read(path)
+			}
+		}
+		result = append(result, svc)
 	}
 
 	return result, nil
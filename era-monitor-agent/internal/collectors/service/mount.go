@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+	"github.com/eracloud/era-monitor-agent/internal/collectors/hostfs"
+	"github.com/eracloud/era-monitor-agent/internal/config"
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// MountMonitor checks that expected mounts are present, come from the right
+// source and are reachable. A share that is not mounted leaves an empty
+// directory on the root disk, which nothing else would notice.
+type MountMonitor struct {
+	mounts  []config.ExpectedMountConfig
+	timeout time.Duration
+
+	mu      sync.Mutex
+	pending map[string]bool
+}
+
+func NewMountMonitor(cfg config.MountServicesConfig) *MountMonitor {
+	timeout := time.Duration(cfg.TimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &MountMonitor{
+		mounts:  cfg.Mounts,
+		timeout: timeout,
+		pending: make(map[string]bool),
+	}
+}
+
+func (m *MountMonitor) Name() string {
+	return "mounts"
+}
+
+func (m *MountMonitor) GetServices() ([]api.ServiceInfo, error) {
+	partitions, err := disk.PartitionsWithContext(context.Background(), true)
+	if err != nil {
+		return nil, err
+	}
+
+	mounted := make(map[string]disk.PartitionStat, len(partitions))
+	for _, p := range partitions {
+		// Later entries shadow earlier ones mounted on the same path.
+		mounted[filepath.Clean(p.Mountpoint)] = p
+	}
+
+	result := make([]api.ServiceInfo, 0, len(m.mounts))
+	for _, expected := range m.mounts {
+		result = append(result, m.check(expected, mounted))
+	}
+	return result, nil
+}
+
+func (m *MountMonitor) check(expected config.ExpectedMountConfig, mounted map[string]disk.PartitionStat) api.ServiceInfo {
+	info := api.ServiceInfo{
+		Name:        expected.Path,
+		DisplayName: "Mount " + expected.Path,
+		Type:        "Mount",
+		Status:      "running",
+		Config: map[string]interface{}{
+			"expected_fstype": expected.FsType,
+			"expected_source": expected.Source,
+		},
+	}
+	fail := func(reason string) api.ServiceInfo {
+		info.Status = "failed"
+		info.Config["reason"] = reason
+		return info
+	}
+
+	p, ok := mounted[filepath.Clean(expected.Path)]
+	if !ok {
+		return fail("not mounted")
+	}
+	info.Config["fstype"] = p.Fstype
+	info.Config["source"] = p.Device
+
+	if expected.FsType != "" && p.Fstype != expected.FsType {
+		return fail(fmt.Sprintf("fstype is %s, expected %s", p.Fstype, expected.FsType))
+	}
+	if expected.Source != "" {
+		if ok, _ := filepath.Match(expected.Source, p.Device); !ok {
+			return fail(fmt.Sprintf("source is %s, expected %s", p.Device, expected.Source))
+		}
+	}
+
+	usage, err := m.usage(p.Mountpoint)
+	if err != nil {
+		return fail(err.Error())
+	}
+	freeGB := float64(usage.Free) / 1024 / 1024 / 1024
+	info.Config["free_gb"] = freeGB
+	info.Config["used_percent"] = usage.UsedPercent
+
+	if expected.MinFreeGB > 0 && freeGB < expected.MinFreeGB {
+		return fail(fmt.Sprintf("%.1f GB free, expected at least %.1f GB", freeGB, expected.MinFreeGB))
+	}
+	return info
+}
+
+// usage stats the mount in a separate goroutine so that a dead NFS or SMB
+// server cannot block the collection cycle. While a previous call is still
+// hanging the mount is reported unreachable without starting another.
+func (m *MountMonitor) usage(path string) (*disk.UsageStat, error) {
+	m.mu.Lock()
+	if m.pending[path] {
+		m.mu.Unlock()
+		return nil, errors.New("unreachable")
+	}
+	m.pending[path] = true
+	m.mu.Unlock()
+
+	type statResult struct {
+		usage *disk.UsageStat
+		err   error
+	}
+	done := make(chan statResult, 1)
+	go func() {
+		usage, err := disk.Usage(hostfs.Root(path))
+		m.mu.Lock()
+		delete(m.pending, path)
+		m.mu.Unlock()
+		done <- statResult{usage: usage, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.usage, r.err
+	case <-time.After(m.timeout):
+		return nil, errors.New("unreachable")
+	}
+}
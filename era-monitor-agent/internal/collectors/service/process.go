@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+	"github.com/eracloud/era-monitor-agent/internal/config"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// ProcessMonitor reports groups of processes matched by name, executable,
+// command line or user as services of type "Process".
+type ProcessMonitor struct {
+	groups []processGroup
+
+	mu       sync.Mutex
+	lastCPU  map[int32]cpuSample
+	lastSeen time.Time
+}
+
+type processGroup struct {
+	cfg     config.ProcessMatchConfig
+	cmdline *regexp.Regexp
+	min     int
+}
+
+type cpuSample struct {
+	created int64
+	total   float64
+}
+
+func NewProcessMonitor(cfg config.ProcessServicesConfig) (*ProcessMonitor, error) {
+	m := &ProcessMonitor{lastCPU: make(map[int32]cpuSample)}
+
+	for _, pc := range cfg.Processes {
+		if pc.Name == "" {
+			return nil, fmt.Errorf("process group without a name")
+		}
+		g := processGroup{cfg: pc, min: pc.MinInstances}
+		if g.min <= 0 {
+			g.min = 1
+		}
+		if pc.Cmdline != "" {
+			re, err := regexp.Compile(pc.Cmdline)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cmdline pattern for %s: %w", pc.Name, err)
+			}
+			g.cmdline = re
+		}
+		if pc.ProcessName == "" && pc.Exe == "" && pc.Cmdline == "" && pc.User == "" {
+			g.cfg.ProcessName = pc.Name
+		}
+		m.groups = append(m.groups, g)
+	}
+
+	return m, nil
+}
+
+func (m *ProcessMonitor) Name() string {
+	return "process"
+}
+
+type processStats struct {
+	pids       []int32
+	cpuPercent float64
+	rssBytes   uint64
+	threads    int32
+	openFiles  int32
+	oldest     int64
+}
+
+func (m *ProcessMonitor) GetServices() ([]api.ServiceInfo, error) {
+	ctx := context.Background()
+	procs, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(m.lastSeen).Seconds()
+	currentCPU := make(map[int32]cpuSample)
+	stats := make([]processStats, len(m.groups))
+	for i := range stats {
+		stats[i].pids = []int32{}
+	}
+
+	for _, p := range procs {
+		for i := range m.groups {
+			if !m.groups[i].matches(ctx, p) {
+				continue
+			}
+
+			s := &stats[i]
+			s.pids = append(s.pids, p.Pid)
+
+			created, _ := p.CreateTimeWithContext(ctx)
+			if created > 0 && (s.oldest == 0 || created < s.oldest) {
+				s.oldest = created
+			}
+			if mem, err := p.MemoryInfoWithContext(ctx); err == nil {
+				s.rssBytes += mem.RSS
+			}
+			if n, err := p.NumThreadsWithContext(ctx); err == nil {
+				s.threads += n
+			}
+			if n, err := p.NumFDsWithContext(ctx); err == nil {
+				s.openFiles += n
+			}
+
+			// CPU usage is the change in CPU time since the last cycle. A PID
+			// reused by a new process has a different creation time.
+			if times, err := p.TimesWithContext(ctx); err == nil {
+				total := times.User + times.System
+				currentCPU[p.Pid] = cpuSample{created: created, total: total}
+				if prev, ok := m.lastCPU[p.Pid]; ok && prev.created == created && elapsed > 0 && total >= prev.total {
+					s.cpuPercent += (total - prev.total) / elapsed * 100
+				}
+			}
+		}
+	}
+
+	m.lastCPU = currentCPU
+	m.lastSeen = now
+
+	result := make([]api.ServiceInfo, 0, len(m.groups))
+	for i, g := range m.groups {
+		s := stats[i]
+		status := "running"
+		if len(s.pids) < g.min {
+			status = "stopped"
+		}
+
+		var uptime int64
+		if s.oldest > 0 {
+			uptime = (now.UnixMilli() - s.oldest) / 1000
+		}
+
+		result = append(result, api.ServiceInfo{
+			Name:        g.cfg.Name,
+			DisplayName: g.cfg.Name,
+			Type:        "Process",
+			Status:      status,
+			Config: map[string]interface{}{
+				"instances":      len(s.pids),
+				"min_instances":  g.min,
+				"pids":           s.pids,
+				"cpu_percent":    s.cpuPercent,
+				"rss_mb":         s.rssBytes / 1024 / 1024,
+				"threads":        s.threads,
+				"open_files":     s.openFiles,
+				"uptime_seconds": uptime,
+			},
+		})
+	}
+
+	return result, nil
+}
+
+// matches checks the configured criteria, cheapest first.
+func (g *processGroup) matches(ctx context.Context, p *process.Process) bool {
+	if g.cfg.ProcessName != "" {
+		name, err := p.NameWithContext(ctx)
+		if err != nil {
+			return false
+		}
+		if ok, _ := filepath.Match(g.cfg.ProcessName, name); !ok {
+			return false
+		}
+	}
+	if g.cfg.User != "" {
+		user, err := p.UsernameWithContext(ctx)
+		if err != nil || !strings.EqualFold(user, g.cfg.User) {
+			return false
+		}
+	}
+	if g.cfg.Exe != "" {
+		exe, err := p.ExeWithContext(ctx)
+		if err != nil {
+			return false
+		}
+		if !matchExe(g.cfg.Exe, exe) {
+			return false
+		}
+	}
+	if g.cmdline != nil {
+		cmdline, err := p.CmdlineWithContext(ctx)
+		if err != nil || !g.cmdline.MatchString(cmdline) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchExe matches an executable path against a glob. A pattern without a
+// path separator is matched against the file name only, so "java" matches
+// "/usr/lib/jvm/bin/java".
+func matchExe(pattern, exe string) bool {
+	if !strings.ContainsAny(pattern, `/\`) {
+		exe = filepath.Base(exe)
+	}
+	ok, _ := filepath.Match(pattern, exe)
+	return ok
+}
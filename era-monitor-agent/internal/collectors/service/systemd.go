@@ -4,17 +4,25 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"path"
 	"strings"
 
 	"github.com/coreos/go-systemd/v22/dbus"
 	"github.com/eracloud/era-monitor-agent/internal/api"
+	"github.com/eracloud/era-monitor-agent/internal/collectors/cgroup"
 )
 
 type SystemdMonitor struct {
 	units []string
+
+	// cgroupUnits are the services, slices and scopes whose cgroup limits
+	// and usage are reported.
+	cgroupUnits []string
+	cgroups     *cgroup.Reader
 }
 
-func NewSystemdMonitor(units []string) (*SystemdMonitor, error) {
+func NewSystemdMonitor(units, cgroupUnits []string) (*SystemdMonitor, error) {
 	// Test connection
 	conn, err := dbus.NewWithContext(context.Background())
 	if err != nil {
@@ -23,7 +31,9 @@ func NewSystemdMonitor(units []string) (*SystemdMonitor, error) {
 	conn.Close()
 
 	return &SystemdMonitor{
-		units: units,
+		units:       units,
+		cgroupUnits: cgroupUnits,
+		cgroups:     cgroup.NewReader(),
 	}, nil
 }
 
@@ -74,9 +84,73 @@ func (m *SystemdMonitor) GetServices() ([]api.ServiceInfo, error) {
 		}
 	}
 
+	result = m.attachCgroups(conn, result)
 	return result, nil
 }
 
+// attachCgroups reports the cgroup of each configured unit next to its
+// service, listing units such as slices that are not otherwise monitored.
+func (m *SystemdMonitor) attachCgroups(conn *dbus.Conn, result []api.ServiceInfo) []api.ServiceInfo {
+	for _, configured := range m.cgroupUnits {
+		unitName := normalizeUnitName(configured)
+		info, err := m.unitCgroup(conn, unitName)
+		if err != nil {
+			continue
+		}
+
+		found := false
+		for i := range result {
+			if result[i].Name == unitName {
+				result[i].Cgroup = info
+				found = true
+				break
+			}
+		}
+		if !found {
+			svc, err := m.getUnitInfo(conn, unitName)
+			if err != nil {
+				continue
+			}
+			svc.Cgroup = info
+			result = append(result, svc)
+		}
+	}
+	return result
+}
+
+// unitTypes are the unit name suffixes systemd knows.
+var unitTypes = map[string]bool{
+	"service": true, "socket": true, "device": true, "mount": true,
+	"automount": true, "swap": true, "target": true, "path": true,
+	"timer": true, "slice": true, "scope": true,
+}
+
+// normalizeUnitName adds the ".service" suffix systemd assumes for a bare
+// unit name such as "nginx".
+func normalizeUnitName(name string) string {
+	if unitTypes[strings.TrimPrefix(path.Ext(name), ".")] {
+		return name
+	}
+	return name + ".service"
+}
+
+// unitCgroup reads the cgroup of a unit. name must be normalised.
+func (m *SystemdMonitor) unitCgroup(conn *dbus.Conn, name string) (*api.CgroupInfo, error) {
+	// ControlGroup lives on the type specific interface, e.g. "Slice".
+	unitType := strings.TrimPrefix(path.Ext(name), ".")
+	unitType = strings.ToUpper(unitType[:1]) + unitType[1:]
+
+	prop, err := conn.GetUnitTypePropertyContext(context.Background(), name, unitType, "ControlGroup")
+	if err != nil {
+		return nil, err
+	}
+	group, _ := prop.Value.Value().(string)
+	if group == "" {
+		return nil, fmt.Errorf("unit %s has no control group", name)
+	}
+	return m.cgroups.Read(strings.TrimPrefix(group, "/"))
+}
+
 func (m *SystemdMonitor) getUnitInfo(conn *dbus.Conn, name string) (api.ServiceInfo, error) {
 	props, err := conn.GetAllPropertiesContext(context.Background(), name)
 	if err != nil {
@@ -10,7 +10,7 @@ import (
 
 type SystemdMonitor struct{}
 
-func NewSystemdMonitor(units []string) (*SystemdMonitor, error) {
+func NewSystemdMonitor(units, cgroupUnits []string) (*SystemdMonitor, error) {
 	return nil, fmt.Errorf("systemd monitor not supported on this platform")
 }
 
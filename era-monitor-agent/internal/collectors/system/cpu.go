@@ -2,19 +2,13 @@ package system
 
 import (
 	"context"
-	"errors"
-	"io"
-	"net"
-	"net/http"
+	"os"
 	"strings"
-	"time"
+	"sync"
 
+	"github.com/eracloud/era-monitor-agent/internal/collectors/hostfs"
 	"github.com/eracloud/era-monitor-agent/internal/config"
-	"github.com/shirou/gopsutil/v3/cpu"
-	"github.com/shirou/gopsutil/v3/disk"
 	"github.com/shirou/gopsutil/v3/host"
-	"github.com/shirou/gopsutil/v3/mem"
-	netstats "github.com/shirou/gopsutil/v3/net"
 	"github.com/shirou/gopsutil/v3/process"
 )
 
@@ -29,36 +23,76 @@ type SystemMetrics struct {
 	Platform        string
 	PlatformVersion string
 	ProcessCount    int
+	// View tells whether the figures describe the host or only the
+	// container the agent runs in.
+	View hostfs.CollectionView
 }
 
 type DiskInfo struct {
-	Name        string
-	MountPoint  string
-	FileSystem  string
-	TotalGB     float64
-	UsedGB      float64
-	UsedPercent float64
+	Name          string
+	MountPoint    string
+	FileSystem    string
+	TotalGB       float64
+	UsedGB        float64
+	UsedPercent   float64
+	InodesTotal   uint64
+	InodesUsed    uint64
+	InodesPercent float64
+	// ReadOnly is set when a filesystem that should be writable is mounted
+	// read-only, for example after ext4 remounted itself on I/O errors.
+	ReadOnly bool
+	// Stale is set when a network filesystem did not answer in time; usage
+	// figures are then unknown.
+	Stale bool
 }
 
 type CollectorResult struct {
 	System  *SystemMetrics
+	CPU     *CPUMetrics
+	Memory  *MemoryMetrics
+	PSI     *PSIMetrics
+	Kernel  *KernelMetrics
 	Disks   []DiskInfo
+	DiskIO  []DiskIOMetrics
 	Network *NetworkMetrics
+	// TopProcesses is nil when the lists are disabled or not due this cycle.
+	TopProcesses *TopProcesses
 }
 
 type SystemCollector struct {
-	config config.SystemCollectorConfig
-}
+	config   config.SystemCollectorConfig
+	publicIP *PublicIPResolver
+
+	netMu   sync.Mutex
+	lastNet *netSample
+
+	statMu       sync.Mutex
+	pendingStats map[string]bool
+
+	diskMu     sync.Mutex
+	lastDiskIO *diskIOSample
+
+	memMu    sync.Mutex
+	lastSwap *swapSample
+
+	psiMu   sync.Mutex
+	lastPSI map[string]uint64
+
+	topProcesses *topProcessesCollector
 
-type NetworkMetrics struct {
-	PrimaryIP string
-	PublicIP  string
-	InBytes   uint64
-	OutBytes  uint64
+	cpuOnce   sync.Once
+	cpuStatic cpuStatic
 }
 
-func NewSystemCollector(cfg config.SystemCollectorConfig) *SystemCollector {
-	return &SystemCollector{config: cfg}
+func NewSystemCollector(cfg config.SystemCollectorConfig, server config.ServerConfig) *SystemCollector {
+	c := &SystemCollector{config: cfg}
+	if cfg.PublicIP.Enabled {
+		c.publicIP = NewPublicIPResolver(cfg.PublicIP, server)
+	}
+	if cfg.TopProcesses.Enabled {
+		c.topProcesses = newTopProcessesCollector(cfg.TopProcesses)
+	}
+	return c
 }
 
 func (c *SystemCollector) Collect(ctx context.Context) (*CollectorResult, error) {
@@ -68,7 +102,7 @@ func (c *SystemCollector) Collect(ctx context.Context) (*CollectorResult, error)
 	}
 
 	if c.config.Network {
-		if netInfo, err := collectNetwork(ctx); err == nil {
+		if netInfo, err := c.collectNetwork(ctx); err == nil {
 			result.Network = netInfo
 		} else {
 			result.Network = &NetworkMetrics{}
@@ -77,40 +111,39 @@ func (c *SystemCollector) Collect(ctx context.Context) (*CollectorResult, error)
 
 	// CPU
 	if c.config.CPU {
-		percent, err := cpu.PercentWithContext(ctx, time.Second, false)
-		if err == nil && len(percent) > 0 {
-			result.System.CPUPercent = percent[0]
+		if cpuMetrics, err := c.collectCPU(ctx); err == nil {
+			result.CPU = cpuMetrics
+			result.System.CPUPercent = cpuMetrics.UsagePercent
 		}
 	}
 
 	// Memory
 	if c.config.RAM {
-		v, err := mem.VirtualMemoryWithContext(ctx)
-		if err == nil {
-			result.System.RAMPercent = v.UsedPercent
-			result.System.RAMUsedMB = v.Used / 1024 / 1024
-			result.System.RAMTotalMB = v.Total / 1024 / 1024
+		if memory, err := c.collectMemory(ctx); err == nil {
+			result.Memory = memory
+			result.System.RAMPercent = memory.UsedPercent
+			result.System.RAMUsedMB = memory.UsedMB
+			result.System.RAMTotalMB = memory.TotalMB
 		}
 	}
 
 	// Disk
 	if c.config.Disk {
-		partitions, err := disk.PartitionsWithContext(ctx, false)
-		if err == nil {
-			for _, p := range partitions {
-				usage, err := disk.UsageWithContext(ctx, p.Mountpoint)
-				if err == nil {
-					result.Disks = append(result.Disks, DiskInfo{
-						Name:        p.Device,
-						MountPoint:  p.Mountpoint,
-						FileSystem:  p.Fstype,
-						TotalGB:     float64(usage.Total) / 1024 / 1024 / 1024,
-						UsedGB:      float64(usage.Used) / 1024 / 1024 / 1024,
-						UsedPercent: usage.UsedPercent,
-					})
-				}
-			}
-		}
+		partitions := c.partitions(ctx)
+		result.Disks = c.collectDisks(ctx, partitions)
+		result.DiskIO = c.collectDiskIO(ctx, partitions)
+	}
+
+	if c.config.PSI {
+		result.PSI = c.collectPSI()
+	}
+
+	if c.config.Kernel {
+		result.Kernel = c.collectKernel()
+	}
+
+	if c.topProcesses != nil {
+		result.TopProcesses = c.topProcesses.Collect(ctx, result.System.CPUPercent, result.System.RAMPercent)
 	}
 
 	// Host Info
@@ -126,6 +159,16 @@ func (c *SystemCollector) Collect(ctx context.Context) (*CollectorResult, error)
 		}
 	}
 
+	// The UTS namespace of a container has its own hostname.
+	if hostfs.Enabled() {
+		if data, err := os.ReadFile(hostfs.Etc("hostname")); err == nil {
+			if name := strings.TrimSpace(string(data)); name != "" {
+				result.System.Hostname = name
+			}
+		}
+	}
+	result.System.View = hostfs.View()
+
 	if result.System.ProcessCount == 0 {
 		if pids, err := process.PidsWithContext(ctx); err == nil {
 			result.System.ProcessCount = len(pids)
@@ -134,85 +177,3 @@ func (c *SystemCollector) Collect(ctx context.Context) (*CollectorResult, error)
 
 	return result, nil
 }
-
-func collectNetwork(ctx context.Context) (*NetworkMetrics, error) {
-	netInfo := &NetworkMetrics{}
-
-	if primaryIP := detectPrimaryIP(); primaryIP != "" {
-		netInfo.PrimaryIP = primaryIP
-	}
-
-	if stats, err := netstats.IOCountersWithContext(ctx, false); err == nil && len(stats) > 0 {
-		netInfo.InBytes = stats[0].BytesRecv
-		netInfo.OutBytes = stats[0].BytesSent
-	}
-
-	publicIP, err := fetchPublicIP(ctx)
-	if err == nil && publicIP != "" {
-		netInfo.PublicIP = publicIP
-	}
-
-	return netInfo, nil
-}
-
-func detectPrimaryIP() string {
-	ifaces, err := net.Interfaces()
-	if err != nil {
-		return ""
-	}
-
-	for _, iface := range ifaces {
-		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
-			continue
-		}
-
-		addrs, err := iface.Addrs()
-		if err != nil {
-			continue
-		}
-
-		for _, addr := range addrs {
-			var ip net.IP
-			switch v := addr.(type) {
-			case *net.IPNet:
-				ip = v.IP
-			case *net.IPAddr:
-				ip = v.IP
-			}
-
-			if ip == nil || ip.IsLoopback() {
-				continue
-			}
-
-			if ipv4 := ip.To4(); ipv4 != nil {
-				return ipv4.String()
-			}
-		}
-	}
-
-	return ""
-}
-
-func fetchPublicIP(ctx context.Context) (string, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.ipify.org", nil)
-	if err != nil {
-		return "", err
-	}
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", errors.New("failed to fetch public IP")
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-
-	return strings.TrimSpace(string(body)), nil
-}
@@ -0,0 +1,200 @@
+package system
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/load"
+)
+
+// cpuSampleWindow is how long CPU times are sampled for each collection.
+const cpuSampleWindow = time.Second
+
+// CPUMetrics breaks CPU usage down by state and by core. Percentages are
+// measured over a one second sampling window.
+type CPUMetrics struct {
+	ModelName     string
+	PhysicalCores int
+	LogicalCores  int
+	FrequencyMHz  float64
+
+	UsagePercent   float64
+	UserPercent    float64
+	SystemPercent  float64
+	NicePercent    float64
+	IOWaitPercent  float64
+	IRQPercent     float64
+	StealPercent   float64
+	IdlePercent    float64
+	PerCorePercent []float64
+
+	Load1         float64
+	Load5         float64
+	Load15        float64
+	Load1PerCore  float64
+	Load5PerCore  float64
+	Load15PerCore float64
+
+	ContextSwitchesPerSec float64
+}
+
+type cpuStatic struct {
+	modelName     string
+	physicalCores int
+	logicalCores  int
+	maxMHz        float64
+}
+
+func (c *SystemCollector) collectCPU(ctx context.Context) (*CPUMetrics, error) {
+	static := c.cpuStaticInfo(ctx)
+
+	before, err := cpu.TimesWithContext(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	miscBefore, miscErr := load.MiscWithContext(ctx)
+	start := time.Now()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(cpuSampleWindow):
+	}
+
+	after, err := cpu.TimesWithContext(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(start).Seconds()
+
+	m := &CPUMetrics{
+		ModelName:     static.modelName,
+		PhysicalCores: static.physicalCores,
+		LogicalCores:  static.logicalCores,
+		FrequencyMHz:  currentFrequencyMHz(),
+	}
+	if m.FrequencyMHz == 0 {
+		m.FrequencyMHz = static.maxMHz
+	}
+
+	var totalBefore, totalAfter cpu.TimesStat
+	prev := make(map[string]cpu.TimesStat, len(before))
+	for _, t := range before {
+		prev[t.CPU] = t
+		addTimes(&totalBefore, t)
+	}
+	for _, t := range after {
+		addTimes(&totalAfter, t)
+		if p, ok := prev[t.CPU]; ok {
+			m.PerCorePercent = append(m.PerCorePercent, timesDelta(p, t).busyPercent())
+		}
+	}
+
+	d := timesDelta(totalBefore, totalAfter)
+	if total := d.total(); total > 0 {
+		m.UsagePercent = d.busyPercent()
+		m.UserPercent = d.User / total * 100
+		m.SystemPercent = d.System / total * 100
+		m.NicePercent = d.Nice / total * 100
+		m.IOWaitPercent = d.Iowait / total * 100
+		m.IRQPercent = (d.Irq + d.Softirq) / total * 100
+		m.StealPercent = d.Steal / total * 100
+		m.IdlePercent = d.Idle / total * 100
+	}
+
+	if avg, err := load.AvgWithContext(ctx); err == nil {
+		m.Load1, m.Load5, m.Load15 = avg.Load1, avg.Load5, avg.Load15
+		if cores := float64(m.LogicalCores); cores > 0 {
+			m.Load1PerCore = avg.Load1 / cores
+			m.Load5PerCore = avg.Load5 / cores
+			m.Load15PerCore = avg.Load15 / cores
+		}
+	}
+
+	if miscErr == nil && elapsed > 0 {
+		if miscAfter, err := load.MiscWithContext(ctx); err == nil && miscAfter.Ctxt >= miscBefore.Ctxt {
+			m.ContextSwitchesPerSec = float64(miscAfter.Ctxt-miscBefore.Ctxt) / elapsed
+		}
+	}
+
+	return m, nil
+}
+
+// cpuStaticInfo reads the CPU model and core counts once; they do not change
+// while the agent runs.
+func (c *SystemCollector) cpuStaticInfo(ctx context.Context) cpuStatic {
+	c.cpuOnce.Do(func() {
+		if infos, err := cpu.InfoWithContext(ctx); err == nil && len(infos) > 0 {
+			c.cpuStatic.modelName = infos[0].ModelName
+			c.cpuStatic.maxMHz = infos[0].Mhz
+		}
+		if n, err := cpu.CountsWithContext(ctx, false); err == nil {
+			c.cpuStatic.physicalCores = n
+		}
+		if n, err := cpu.CountsWithContext(ctx, true); err == nil {
+			c.cpuStatic.logicalCores = n
+		}
+		if c.cpuStatic.logicalCores == 0 {
+			c.cpuStatic.logicalCores = runtime.NumCPU()
+		}
+	})
+	return c.cpuStatic
+}
+
+type cpuTimes cpu.TimesStat
+
+func addTimes(sum *cpu.TimesStat, t cpu.TimesStat) {
+	sum.User += t.User
+	sum.System += t.System
+	sum.Idle += t.Idle
+	sum.Nice += t.Nice
+	sum.Iowait += t.Iowait
+	sum.Irq += t.Irq
+	sum.Softirq += t.Softirq
+	sum.Steal += t.Steal
+	sum.Guest += t.Guest
+	sum.GuestNice += t.GuestNice
+}
+
+// timesDelta returns the time spent in each state between two samples.
+// Guest time is already accounted for in user and nice time on Linux, so it
+// is left out.
+func timesDelta(before, after cpu.TimesStat) cpuTimes {
+	return cpuTimes{
+		User:    nonNegative(after.User - after.Guest - (before.User - before.Guest)),
+		System:  nonNegative(after.System - before.System),
+		Idle:    nonNegative(after.Idle - before.Idle),
+		Nice:    nonNegative(after.Nice - after.GuestNice - (before.Nice - before.GuestNice)),
+		Iowait:  nonNegative(after.Iowait - before.Iowait),
+		Irq:     nonNegative(after.Irq - before.Irq),
+		Softirq: nonNegative(after.Softirq - before.Softirq),
+		Steal:   nonNegative(after.Steal - before.Steal),
+	}
+}
+
+func (t cpuTimes) total() float64 {
+	return t.User + t.System + t.Idle + t.Nice + t.Iowait + t.Irq + t.Softirq + t.Steal
+}
+
+// busyPercent matches gopsutil's cpu.Percent: everything but idle and iowait
+// counts as busy.
+func (t cpuTimes) busyPercent() float64 {
+	total := t.total()
+	if total <= 0 {
+		return 0
+	}
+	busy := total - t.Idle - t.Iowait
+	if busy < 0 {
+		busy = 0
+	}
+	return busy / total * 100
+}
+
+func nonNegative(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	return v
+}
@@ -0,0 +1,52 @@
+//go:build linux
+
+package system
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/eracloud/era-monitor-agent/internal/collectors/hostfs"
+)
+
+// currentFrequencyMHz returns the average current clock speed across cores,
+// from cpufreq when available and /proc/cpuinfo otherwise.
+func currentFrequencyMHz() float64 {
+	paths, _ := filepath.Glob(hostfs.Sys("devices/system/cpu/cpu[0-9]*/cpufreq/scaling_cur_freq"))
+	var sum float64
+	var n int
+	for _, path := range paths {
+		if khz, err := strconv.ParseFloat(readSysfsString(path), 64); err == nil && khz > 0 {
+			sum += khz / 1000
+			n++
+		}
+	}
+	if n > 0 {
+		return sum / float64(n)
+	}
+
+	f, err := os.Open(hostfs.Proc("cpuinfo"))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || strings.TrimSpace(key) != "cpu MHz" {
+			continue
+		}
+		if mhz, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+			sum += mhz
+			n++
+		}
+	}
+	if n > 0 {
+		return sum / float64(n)
+	}
+	return 0
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package system
+
+// currentFrequencyMHz is not available on this platform; the nominal speed
+// reported by cpu.Info is used instead.
+func currentFrequencyMHz() float64 {
+	return 0
+}
@@ -0,0 +1,138 @@
+package system
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eracloud/era-monitor-agent/internal/collectors/hostfs"
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// ignoredBlockDevices are virtual devices whose counters are noise.
+var ignoredBlockDevices = []string{"loop*", "ram*"}
+
+// DiskIOMetrics describes the load on one block device over the last
+// collection interval. It is computed from counter deltas, so the first
+// cycle after start only establishes the baseline and reports no devices.
+type DiskIOMetrics struct {
+	Device      string
+	MountPoints []string
+
+	ReadBytesPerSec  float64
+	WriteBytesPerSec float64
+	ReadIOPS         float64
+	WriteIOPS        float64
+	// AwaitMs is the average time an I/O request took, queueing included.
+	AwaitMs float64
+	// UtilPercent is the share of wall time the device was busy.
+	UtilPercent float64
+	// QueueDepth is the average number of requests in flight.
+	QueueDepth float64
+	InFlight   uint64
+}
+
+type diskIOSample struct {
+	at       time.Time
+	counters map[string]disk.IOCountersStat
+}
+
+// collectDiskIO computes per-device rates and maps devices back to the given
+// partitions.
+func (c *SystemCollector) collectDiskIO(ctx context.Context, partitions []disk.PartitionStat) []DiskIOMetrics {
+	counters, err := disk.IOCountersWithContext(ctx)
+	if err != nil {
+		return nil
+	}
+
+	now := time.Now()
+	c.diskMu.Lock()
+	prev := c.lastDiskIO
+	c.lastDiskIO = &diskIOSample{at: now, counters: counters}
+	c.diskMu.Unlock()
+
+	if prev == nil {
+		return nil
+	}
+	secs := now.Sub(prev.at).Seconds()
+	if secs <= 0 {
+		return nil
+	}
+
+	mounts := make(map[string][]string)
+	for _, p := range partitions {
+		key := blockDeviceName(p.Device)
+		mounts[key] = append(mounts[key], p.Mountpoint)
+	}
+
+	names := make([]string, 0, len(counters))
+	for name := range counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var result []DiskIOMetrics
+	for _, name := range names {
+		if blockDeviceIgnored(name) {
+			continue
+		}
+		cur := counters[name]
+		old, ok := prev.counters[name]
+		if !ok {
+			continue
+		}
+
+		m := DiskIOMetrics{
+			Device:           name,
+			MountPoints:      mounts[name],
+			ReadBytesPerSec:  rate(cur.ReadBytes, old.ReadBytes, secs),
+			WriteBytesPerSec: rate(cur.WriteBytes, old.WriteBytes, secs),
+			ReadIOPS:         rate(cur.ReadCount, old.ReadCount, secs),
+			WriteIOPS:        rate(cur.WriteCount, old.WriteCount, secs),
+			InFlight:         cur.IopsInProgress,
+		}
+
+		if cur.ReadCount >= old.ReadCount && cur.WriteCount >= old.WriteCount &&
+			cur.ReadTime >= old.ReadTime && cur.WriteTime >= old.WriteTime {
+			ios := (cur.ReadCount - old.ReadCount) + (cur.WriteCount - old.WriteCount)
+			if ios > 0 {
+				m.AwaitMs = float64((cur.ReadTime-old.ReadTime)+(cur.WriteTime-old.WriteTime)) / float64(ios)
+			}
+		}
+		// IoTime and WeightedIO are in milliseconds.
+		m.UtilPercent = rate(cur.IoTime, old.IoTime, secs) / 1000 * 100
+		if m.UtilPercent > 100 {
+			m.UtilPercent = 100
+		}
+		m.QueueDepth = rate(cur.WeightedIO, old.WeightedIO, secs) / 1000
+
+		result = append(result, m)
+	}
+
+	return result
+}
+
+// blockDeviceName returns the name disk.IOCounters uses for a partition's
+// device. Linux device paths may be symlinks, such as /dev/mapper/* pointing
+// at /dev/dm-*, and are resolved through the host root in host mode; other
+// platforms use the device string as is.
+func blockDeviceName(device string) string {
+	if !strings.HasPrefix(device, "/dev/") {
+		return device
+	}
+	if resolved, err := filepath.EvalSymlinks(hostfs.Root(device)); err == nil {
+		return filepath.Base(resolved)
+	}
+	return strings.TrimPrefix(device, "/dev/")
+}
+
+func blockDeviceIgnored(name string) bool {
+	for _, pattern := range ignoredBlockDevices {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
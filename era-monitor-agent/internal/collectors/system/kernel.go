@@ -0,0 +1,44 @@
+package system
+
+// KernelMetrics reports kernel tables that can run out independently of CPU
+// and memory. A nil field means the limit is not available on this host.
+type KernelMetrics struct {
+	FileHandles *KernelLimit
+	Conntrack   *KernelLimit
+	// PIDs counts every task, threads included, against kernel.pid_max since
+	// threads take PIDs too.
+	PIDs      *KernelLimit
+	Threads   *KernelLimit
+	Processes int
+	Sockets   *SocketStats
+}
+
+// KernelLimit is the current usage of a kernel table and its maximum.
+type KernelLimit struct {
+	Used    uint64
+	Max     uint64
+	Percent float64
+}
+
+// SocketStats holds the socket counters of /proc/net/sockstat. TCPMemPages
+// is compared with the maximum of net.ipv4.tcp_mem and orphans with
+// net.ipv4.tcp_max_orphans.
+type SocketStats struct {
+	Used             uint64
+	TCPInUse         uint64
+	TCPOrphan        uint64
+	TCPTimeWait      uint64
+	TCPAlloc         uint64
+	TCPMemPages      uint64
+	UDPInUse         uint64
+	TCPOrphanPercent float64
+	TCPMemPercent    float64
+}
+
+func newKernelLimit(used, max uint64) *KernelLimit {
+	l := &KernelLimit{Used: used, Max: max}
+	if max > 0 {
+		l.Percent = float64(used) / float64(max) * 100
+	}
+	return l
+}
@@ -0,0 +1,141 @@
+//go:build linux
+
+package system
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/eracloud/era-monitor-agent/internal/collectors/hostfs"
+)
+
+func (c *SystemCollector) collectKernel() *KernelMetrics {
+	m := &KernelMetrics{}
+
+	// file-nr holds allocated handles, allocated but unused handles and the
+	// maximum.
+	if fields := strings.Fields(readSysfsString(hostfs.Proc("sys/fs/file-nr"))); len(fields) == 3 {
+		allocated, _ := strconv.ParseUint(fields[0], 10, 64)
+		unused, _ := strconv.ParseUint(fields[1], 10, 64)
+		max, _ := strconv.ParseUint(fields[2], 10, 64)
+		if allocated >= unused {
+			m.FileHandles = newKernelLimit(allocated-unused, max)
+		}
+	}
+
+	// The conntrack files only exist while the nf_conntrack module is loaded.
+	// The table size is global, but the entry count belongs to a network
+	// namespace and /proc/sys follows the reader's, so it is read from
+	// /proc/net, which NetProc resolves through PID 1 in host mode.
+	if count, ok := readConntrackCount(); ok {
+		if max, ok := readUint(hostfs.Proc("sys/net/netfilter/nf_conntrack_max")); ok {
+			m.Conntrack = newKernelLimit(count, max)
+		}
+	}
+
+	// The fourth field of loadavg is "runnable/total" scheduling entities,
+	// i.e. every thread on the system.
+	if fields := strings.Fields(readSysfsString(hostfs.Proc("loadavg"))); len(fields) >= 4 {
+		if _, total, ok := strings.Cut(fields[3], "/"); ok {
+			if tasks, err := strconv.ParseUint(total, 10, 64); err == nil {
+				if max, ok := readUint(hostfs.Proc("sys/kernel/pid_max")); ok {
+					m.PIDs = newKernelLimit(tasks, max)
+				}
+				if max, ok := readUint(hostfs.Proc("sys/kernel/threads-max")); ok {
+					m.Threads = newKernelLimit(tasks, max)
+				}
+			}
+		}
+	}
+
+	if entries, err := os.ReadDir(hostfs.Proc()); err == nil {
+		for _, entry := range entries {
+			if _, err := strconv.Atoi(entry.Name()); err == nil && entry.IsDir() {
+				m.Processes++
+			}
+		}
+	}
+
+	m.Sockets = readSockstat()
+
+	return m
+}
+
+// readSockstat parses lines such as
+//
+//	sockets: used 19
+//	TCP: inuse 5 orphan 0 tw 5 alloc 5 mem 0
+func readSockstat() *SocketStats {
+	f, err := os.Open(hostfs.NetProc("sockstat"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		proto := strings.TrimSuffix(fields[0], ":")
+		for i := 1; i+1 < len(fields); i += 2 {
+			if v, err := strconv.ParseUint(fields[i+1], 10, 64); err == nil {
+				values[proto+"."+fields[i]] = v
+			}
+		}
+	}
+
+	s := &SocketStats{
+		Used:        values["sockets.used"],
+		TCPInUse:    values["TCP.inuse"],
+		TCPOrphan:   values["TCP.orphan"],
+		TCPTimeWait: values["TCP.tw"],
+		TCPAlloc:    values["TCP.alloc"],
+		TCPMemPages: values["TCP.mem"],
+		UDPInUse:    values["UDP.inuse"],
+	}
+
+	// tcp_max_orphans and tcp_mem are global limits, so the namespace they
+	// are read from does not matter.
+	if max, ok := readUint(hostfs.Proc("sys/net/ipv4/tcp_max_orphans")); ok && max > 0 {
+		s.TCPOrphanPercent = float64(s.TCPOrphan) / float64(max) * 100
+	}
+	// tcp_mem is "min pressure max" in pages; usage is measured against max.
+	if fields := strings.Fields(readSysfsString(hostfs.Proc("sys/net/ipv4/tcp_mem"))); len(fields) == 3 {
+		if max, err := strconv.ParseUint(fields[2], 10, 64); err == nil && max > 0 {
+			s.TCPMemPercent = float64(s.TCPMemPages) / float64(max) * 100
+		}
+	}
+
+	return s
+}
+
+// readConntrackCount reads the "entries" column of /proc/net/stat/nf_conntrack.
+// Every per-CPU line repeats the namespace-wide count, in hex.
+func readConntrackCount() (uint64, bool) {
+	data, err := os.ReadFile(hostfs.NetProc("stat/nf_conntrack"))
+	if err != nil {
+		return 0, false
+	}
+	lines := strings.Split(string(data), "\n")
+	if len(lines) < 2 {
+		return 0, false
+	}
+	header, values := strings.Fields(lines[0]), strings.Fields(lines[1])
+	for i, name := range header {
+		if name == "entries" && i < len(values) {
+			v, err := strconv.ParseUint(values[i], 16, 64)
+			return v, err == nil
+		}
+	}
+	return 0, false
+}
+
+func readUint(path string) (uint64, bool) {
+	v, err := strconv.ParseUint(readSysfsString(path), 10, 64)
+	return v, err == nil
+}
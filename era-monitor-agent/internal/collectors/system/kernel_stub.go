@@ -0,0 +1,9 @@
+//go:build !linux
+
+package system
+
+// collectKernel is not implemented on this platform; the kernel tables it
+// reports are Linux specific.
+func (c *SystemCollector) collectKernel() *KernelMetrics {
+	return nil
+}
@@ -0,0 +1,99 @@
+package system
+
+import (
+	"context"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+const bytesPerMB = 1024 * 1024
+
+// MemoryMetrics extends the RAM figures of SystemMetrics with what is needed
+// to judge real memory pressure. Page cache can be reclaimed, so AvailableMB
+// rather than FreeMB is the figure to alert on. Sizes are in MB; swap rates
+// are in bytes per second and zero on the first cycle.
+type MemoryMetrics struct {
+	TotalMB     uint64
+	AvailableMB uint64
+	UsedMB      uint64
+	UsedPercent float64
+	FreeMB      uint64
+	BuffersMB   uint64
+	CachedMB    uint64
+	SharedMB    uint64
+	SlabMB      uint64
+
+	SwapTotalMB        uint64
+	SwapUsedMB         uint64
+	SwapUsedPercent    float64
+	SwapInBytesPerSec  float64
+	SwapOutBytesPerSec float64
+
+	CommittedMB   uint64
+	CommitLimitMB uint64
+	CommitPercent float64
+	DirtyMB       uint64
+	WritebackMB   uint64
+
+	HugePagesTotal uint64
+	HugePagesFree  uint64
+	HugePagesRsvd  uint64
+	HugePageSizeKB uint64
+}
+
+type swapSample struct {
+	at   time.Time
+	sin  uint64
+	sout uint64
+}
+
+func (c *SystemCollector) collectMemory(ctx context.Context) (*MemoryMetrics, error) {
+	v, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &MemoryMetrics{
+		TotalMB:        v.Total / bytesPerMB,
+		AvailableMB:    v.Available / bytesPerMB,
+		UsedMB:         v.Used / bytesPerMB,
+		UsedPercent:    v.UsedPercent,
+		FreeMB:         v.Free / bytesPerMB,
+		BuffersMB:      v.Buffers / bytesPerMB,
+		CachedMB:       v.Cached / bytesPerMB,
+		SharedMB:       v.Shared / bytesPerMB,
+		SlabMB:         v.Slab / bytesPerMB,
+		CommittedMB:    v.CommittedAS / bytesPerMB,
+		CommitLimitMB:  v.CommitLimit / bytesPerMB,
+		DirtyMB:        v.Dirty / bytesPerMB,
+		WritebackMB:    v.WriteBack / bytesPerMB,
+		HugePagesTotal: v.HugePagesTotal,
+		HugePagesFree:  v.HugePagesFree,
+		HugePagesRsvd:  v.HugePagesRsvd,
+		HugePageSizeKB: v.HugePageSize / 1024,
+	}
+	if v.CommitLimit > 0 {
+		m.CommitPercent = float64(v.CommittedAS) / float64(v.CommitLimit) * 100
+	}
+
+	if swap, err := mem.SwapMemoryWithContext(ctx); err == nil {
+		m.SwapTotalMB = swap.Total / bytesPerMB
+		m.SwapUsedMB = swap.Used / bytesPerMB
+		m.SwapUsedPercent = swap.UsedPercent
+
+		now := time.Now()
+		c.memMu.Lock()
+		prev := c.lastSwap
+		c.lastSwap = &swapSample{at: now, sin: swap.Sin, sout: swap.Sout}
+		c.memMu.Unlock()
+
+		if prev != nil {
+			secs := now.Sub(prev.at).Seconds()
+			m.SwapInBytesPerSec = rate(swap.Sin, prev.sin, secs)
+			m.SwapOutBytesPerSec = rate(swap.Sout, prev.sout, secs)
+		}
+	}
+
+	return m, nil
+}
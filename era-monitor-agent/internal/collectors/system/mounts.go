@@ -0,0 +1,215 @@
+package system
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/eracloud/era-monitor-agent/internal/collectors/hostfs"
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// readOnlyFileSystems are filesystem types that are read-only by design and
+// must not be reported as unexpectedly read-only.
+var readOnlyFileSystems = map[string]bool{
+	"iso9660":  true,
+	"udf":      true,
+	"squashfs": true,
+	"erofs":    true,
+	"cramfs":   true,
+}
+
+// networkFileSystems can hang in statfs when the server goes away, so they
+// are queried with a timeout. FUSE filesystems are served by a userspace
+// process that can hang just the same and are treated alike, see
+// needsStatTimeout.
+var networkFileSystems = map[string]bool{
+	"nfs":       true,
+	"nfs4":      true,
+	"cifs":      true,
+	"smb3":      true,
+	"smbfs":     true,
+	"9p":        true,
+	"afs":       true,
+	"ceph":      true,
+	"glusterfs": true,
+	"davfs":     true,
+}
+
+func needsStatTimeout(fstype string) bool {
+	return networkFileSystems[fstype] || fstype == "fuse" || strings.HasPrefix(fstype, "fuse.")
+}
+
+const defaultStatTimeout = 2 * time.Second
+
+// partitions returns the mounted filesystems that pass the configured
+// filters, with bind mounts of the same device reduced to one entry. Every
+// mount is listed, including network and FUSE filesystems that have no
+// backing device; pseudo filesystems are left to the exclude filters.
+func (c *SystemCollector) partitions(ctx context.Context) []disk.PartitionStat {
+	all, err := disk.PartitionsWithContext(ctx, true)
+	if err != nil {
+		return nil
+	}
+
+	var result []disk.PartitionStat
+	seen := make(map[string]int)
+	for _, p := range all {
+		if !c.partitionIncluded(p) {
+			continue
+		}
+
+		// A device mounted more than once is the same filesystem; keep the
+		// shortest mountpoint. Pseudo devices like "tmpfs" are not paths and
+		// are never merged.
+		if strings.HasPrefix(p.Device, "/") || strings.Contains(p.Device, ":/") {
+			if i, ok := seen[p.Device]; ok {
+				if len(p.Mountpoint) < len(result[i].Mountpoint) {
+					result[i] = p
+				}
+				continue
+			}
+			seen[p.Device] = len(result)
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+func (c *SystemCollector) partitionIncluded(p disk.PartitionStat) bool {
+	f := c.config.DiskFilter
+	if len(f.IncludeFsTypes) > 0 && !matchAny(f.IncludeFsTypes, p.Fstype) {
+		return false
+	}
+	if len(f.IncludeMountPoints) > 0 && !matchPath(f.IncludeMountPoints, p.Mountpoint) {
+		return false
+	}
+	if matchAny(f.ExcludeFsTypes, p.Fstype) ||
+		matchPath(f.ExcludeMountPoints, p.Mountpoint) ||
+		matchAny(f.ExcludeDevices, p.Device) {
+		return false
+	}
+	return true
+}
+
+// collectDisks reports capacity and inode usage for each partition.
+func (c *SystemCollector) collectDisks(ctx context.Context, partitions []disk.PartitionStat) []DiskInfo {
+	disks := make([]DiskInfo, 0, len(partitions))
+	for _, p := range partitions {
+		usage, stale, err := c.usage(ctx, p)
+		if stale {
+			disks = append(disks, DiskInfo{
+				Name:       p.Device,
+				MountPoint: p.Mountpoint,
+				FileSystem: p.Fstype,
+				Stale:      true,
+			})
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		disks = append(disks, DiskInfo{
+			Name:          p.Device,
+			MountPoint:    p.Mountpoint,
+			FileSystem:    p.Fstype,
+			TotalGB:       float64(usage.Total) / 1024 / 1024 / 1024,
+			UsedGB:        float64(usage.Used) / 1024 / 1024 / 1024,
+			UsedPercent:   usage.UsedPercent,
+			InodesTotal:   usage.InodesTotal,
+			InodesUsed:    usage.InodesUsed,
+			InodesPercent: usage.InodesUsedPercent,
+			ReadOnly:      unexpectedReadOnly(p),
+		})
+	}
+	return disks
+}
+
+// usage returns the usage of a filesystem, through the host root in host
+// mode. Network and FUSE filesystems are queried in
+// a separate goroutine; if the call does not return within the stat timeout
+// the mount is reported as stale. A hung call is not retried until it
+// finally returns, so a dead server costs at most one blocked goroutine.
+func (c *SystemCollector) usage(ctx context.Context, p disk.PartitionStat) (*disk.UsageStat, bool, error) {
+	if !needsStatTimeout(p.Fstype) {
+		usage, err := disk.UsageWithContext(ctx, hostfs.Root(p.Mountpoint))
+		return usage, false, err
+	}
+
+	c.statMu.Lock()
+	if c.pendingStats[p.Mountpoint] {
+		c.statMu.Unlock()
+		return nil, true, nil
+	}
+	if c.pendingStats == nil {
+		c.pendingStats = make(map[string]bool)
+	}
+	c.pendingStats[p.Mountpoint] = true
+	c.statMu.Unlock()
+
+	type statResult struct {
+		usage *disk.UsageStat
+		err   error
+	}
+	done := make(chan statResult, 1)
+	go func() {
+		usage, err := disk.Usage(hostfs.Root(p.Mountpoint))
+		c.statMu.Lock()
+		delete(c.pendingStats, p.Mountpoint)
+		c.statMu.Unlock()
+		done <- statResult{usage: usage, err: err}
+	}()
+
+	timeout := time.Duration(c.config.DiskFilter.StatTimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = defaultStatTimeout
+	}
+
+	select {
+	case r := <-done:
+		return r.usage, false, r.err
+	case <-time.After(timeout):
+		return nil, true, nil
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+}
+
+// unexpectedReadOnly reports whether a normally writable filesystem is
+// mounted with the "ro" option.
+func unexpectedReadOnly(p disk.PartitionStat) bool {
+	if readOnlyFileSystems[p.Fstype] {
+		return false
+	}
+	for _, opt := range p.Opts {
+		if opt == "ro" {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPath is like matchAny, but a pattern that matches a directory also
+// matches everything below it, so "/snap/*" covers "/snap/core/123".
+func matchPath(patterns []string, path string) bool {
+	for {
+		if matchAny(patterns, path) {
+			return true
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return false
+		}
+		path = parent
+	}
+}
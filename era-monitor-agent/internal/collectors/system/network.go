@@ -0,0 +1,278 @@
+package system
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"time"
+
+	"github.com/eracloud/era-monitor-agent/internal/collectors/hostfs"
+	netstats "github.com/shirou/gopsutil/v3/net"
+)
+
+type NetworkMetrics struct {
+	PrimaryIP      string
+	PublicIP       string
+	PublicIPv6     string
+	PublicIPSource string
+	InBytes        uint64
+	OutBytes       uint64
+	Interfaces     []InterfaceMetrics
+}
+
+// InterfaceMetrics describes one network interface. Rates are per second and
+// computed from the counter deltas since the previous collection; they are
+// zero on the first cycle.
+type InterfaceMetrics struct {
+	Name      string
+	MAC       string
+	MTU       int
+	IPv4      []string
+	IPv6      []string
+	Up        bool
+	OperState string
+	SpeedMbps int
+	Duplex    string
+	IsDefault bool
+
+	BytesRecv uint64
+	BytesSent uint64
+
+	BytesRecvPerSec   float64
+	BytesSentPerSec   float64
+	PacketsRecvPerSec float64
+	PacketsSentPerSec float64
+	ErrorsInPerSec    float64
+	ErrorsOutPerSec   float64
+	DropsInPerSec     float64
+	DropsOutPerSec    float64
+}
+
+type netSample struct {
+	at       time.Time
+	counters map[string]netstats.IOCountersStat
+}
+
+// ifaceInfo is the static description of a network interface.
+type ifaceInfo struct {
+	Name     string
+	MAC      string
+	MTU      int
+	Up       bool
+	Loopback bool
+	IPv4     []string
+	IPv6     []string
+}
+
+func (c *SystemCollector) collectNetwork(ctx context.Context) (*NetworkMetrics, error) {
+	netInfo := &NetworkMetrics{}
+
+	// In host mode without the host's network namespace, the agent's own
+	// interfaces are the container's; read the host's from sysfs and procfs.
+	var ifaces []ifaceInfo
+	if hostfs.Enabled() && !hostfs.SharesNetNamespace() {
+		var defaultIface string
+		ifaces, defaultIface = hostInterfaces()
+		for _, iface := range ifaces {
+			if iface.Name == defaultIface && len(iface.IPv4) > 0 {
+				netInfo.PrimaryIP = iface.IPv4[0]
+			}
+		}
+	} else {
+		ifaces = localInterfaces()
+		// The primary IP is the source address of the default route; fall
+		// back to the first usable interface address when there is no
+		// default route.
+		if primaryIP := detectDefaultRouteIP(); primaryIP != "" {
+			netInfo.PrimaryIP = primaryIP
+		} else if primaryIP := detectPrimaryIP(); primaryIP != "" {
+			netInfo.PrimaryIP = primaryIP
+		}
+	}
+
+	if stats, err := netstats.IOCountersByFileWithContext(ctx, false, hostfs.NetProc("dev")); err == nil && len(stats) > 0 {
+		netInfo.InBytes = stats[0].BytesRecv
+		netInfo.OutBytes = stats[0].BytesSent
+	}
+
+	netInfo.Interfaces = c.collectInterfaces(ctx, ifaces, netInfo.PrimaryIP)
+
+	if c.publicIP != nil {
+		publicIP := c.publicIP.Resolve(ctx)
+		netInfo.PublicIP = publicIP.IPv4
+		netInfo.PublicIPv6 = publicIP.IPv6
+		netInfo.PublicIPSource = publicIP.Source
+	}
+
+	return netInfo, nil
+}
+
+// localInterfaces lists the interfaces of the agent's network namespace.
+func localInterfaces() []ifaceInfo {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+
+	result := make([]ifaceInfo, 0, len(ifaces))
+	for _, iface := range ifaces {
+		info := ifaceInfo{
+			Name:     iface.Name,
+			MAC:      iface.HardwareAddr.String(),
+			MTU:      iface.MTU,
+			Up:       iface.Flags&net.FlagUp != 0,
+			Loopback: iface.Flags&net.FlagLoopback != 0,
+		}
+		if addrs, err := iface.Addrs(); err == nil {
+			for _, addr := range addrs {
+				ipNet, ok := addr.(*net.IPNet)
+				if !ok {
+					continue
+				}
+				if ipNet.IP.To4() != nil {
+					info.IPv4 = append(info.IPv4, ipNet.IP.String())
+				} else {
+					info.IPv6 = append(info.IPv6, ipNet.IP.String())
+				}
+			}
+		}
+		result = append(result, info)
+	}
+	return result
+}
+
+func (c *SystemCollector) collectInterfaces(ctx context.Context, ifaces []ifaceInfo, primaryIP string) []InterfaceMetrics {
+	now := time.Now()
+	counters := make(map[string]netstats.IOCountersStat)
+	if stats, err := netstats.IOCountersByFileWithContext(ctx, true, hostfs.NetProc("dev")); err == nil {
+		for _, s := range stats {
+			counters[s.Name] = s
+		}
+	}
+
+	c.netMu.Lock()
+	prev := c.lastNet
+	c.lastNet = &netSample{at: now, counters: counters}
+	c.netMu.Unlock()
+
+	var result []InterfaceMetrics
+	for _, iface := range ifaces {
+		if iface.Loopback || c.interfaceExcluded(iface.Name) {
+			continue
+		}
+
+		m := InterfaceMetrics{
+			Name: iface.Name,
+			MAC:  iface.MAC,
+			MTU:  iface.MTU,
+			IPv4: iface.IPv4,
+			IPv6: iface.IPv6,
+			Up:   iface.Up,
+		}
+		for _, ip := range iface.IPv4 {
+			if ip == primaryIP {
+				m.IsDefault = true
+			}
+		}
+
+		link := readLinkInfo(iface.Name)
+		m.OperState = link.OperState
+		m.SpeedMbps = link.SpeedMbps
+		m.Duplex = link.Duplex
+
+		if cur, ok := counters[iface.Name]; ok {
+			m.BytesRecv = cur.BytesRecv
+			m.BytesSent = cur.BytesSent
+
+			if prev != nil {
+				if old, ok := prev.counters[iface.Name]; ok {
+					secs := now.Sub(prev.at).Seconds()
+					m.BytesRecvPerSec = rate(cur.BytesRecv, old.BytesRecv, secs)
+					m.BytesSentPerSec = rate(cur.BytesSent, old.BytesSent, secs)
+					m.PacketsRecvPerSec = rate(cur.PacketsRecv, old.PacketsRecv, secs)
+					m.PacketsSentPerSec = rate(cur.PacketsSent, old.PacketsSent, secs)
+					m.ErrorsInPerSec = rate(cur.Errin, old.Errin, secs)
+					m.ErrorsOutPerSec = rate(cur.Errout, old.Errout, secs)
+					m.DropsInPerSec = rate(cur.Dropin, old.Dropin, secs)
+					m.DropsOutPerSec = rate(cur.Dropout, old.Dropout, secs)
+				}
+			}
+		}
+
+		result = append(result, m)
+	}
+
+	return result
+}
+
+func (c *SystemCollector) interfaceExcluded(name string) bool {
+	for _, pattern := range c.config.InterfaceExclude {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// rate returns the per-second change of a counter. A counter that went
+// backwards was reset, so no rate can be computed for this interval.
+func rate(cur, prev uint64, secs float64) float64 {
+	if secs <= 0 || cur < prev {
+		return 0
+	}
+	return float64(cur-prev) / secs
+}
+
+// detectDefaultRouteIP returns the local address the kernel would use to
+// reach the internet. Connecting a UDP socket only performs the route lookup;
+// no packet is sent.
+func detectDefaultRouteIP() string {
+	conn, err := net.Dial("udp4", "192.0.2.1:9")
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+
+	if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok && !addr.IP.IsUnspecified() {
+		return addr.IP.String()
+	}
+	return ""
+}
+
+func detectPrimaryIP() string {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return ""
+	}
+
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+
+		for _, addr := range addrs {
+			var ip net.IP
+			switch v := addr.(type) {
+			case *net.IPNet:
+				ip = v.IP
+			case *net.IPAddr:
+				ip = v.IP
+			}
+
+			if ip == nil || ip.IsLoopback() {
+				continue
+			}
+
+			if ipv4 := ip.To4(); ipv4 != nil {
+				return ipv4.String()
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,169 @@
+//go:build linux
+
+package system
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/eracloud/era-monitor-agent/internal/collectors/hostfs"
+)
+
+// hostInterfaces lists the interfaces of the host's network namespace for an
+// agent that runs in a container with a namespace of its own. Interfaces come
+// from the host's sysfs and addresses from the procfs files of the host's
+// PID 1. It also returns the interface of the default route.
+func hostInterfaces() ([]ifaceInfo, string) {
+	entries, err := os.ReadDir(hostfs.Sys("class/net"))
+	if err != nil {
+		return nil, ""
+	}
+
+	routes, defaultIface := readHostRoutes()
+	ipv4 := make(map[string][]string)
+	for _, ip := range readLocalIPv4() {
+		if name := routeInterface(routes, ip); name != "" {
+			ipv4[name] = append(ipv4[name], ip.String())
+		}
+	}
+	ipv6 := readIPv6Addresses()
+
+	var result []ifaceInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		dir := hostfs.Sys("class/net", name)
+
+		info := ifaceInfo{
+			Name: name,
+			MAC:  readSysfsString(dir + "/address"),
+			IPv4: ipv4[name],
+			IPv6: ipv6[name],
+		}
+		info.MTU, _ = strconv.Atoi(readSysfsString(dir + "/mtu"))
+		if flags, err := strconv.ParseUint(strings.TrimPrefix(readSysfsString(dir+"/flags"), "0x"), 16, 32); err == nil {
+			info.Up = flags&uint64(net.FlagUp) != 0
+			// IFF_LOOPBACK is 0x8 in the kernel flags.
+			info.Loopback = flags&0x8 != 0
+		}
+		if info.MAC == "00:00:00:00:00:00" {
+			info.MAC = ""
+		}
+		result = append(result, info)
+	}
+	return result, defaultIface
+}
+
+type hostRoute struct {
+	iface string
+	dest  uint32
+	mask  uint32
+}
+
+// readHostRoutes parses /proc/net/route. Addresses are little-endian hex.
+func readHostRoutes() ([]hostRoute, string) {
+	f, err := os.Open(hostfs.NetProc("route"))
+	if err != nil {
+		return nil, ""
+	}
+	defer f.Close()
+
+	var routes []hostRoute
+	defaultIface := ""
+	defaultMetric := -1
+
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 {
+			continue
+		}
+		dest, err1 := strconv.ParseUint(fields[1], 16, 32)
+		mask, err2 := strconv.ParseUint(fields[7], 16, 32)
+		metric, err3 := strconv.Atoi(fields[6])
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+
+		if dest == 0 && mask == 0 {
+			if defaultMetric < 0 || metric < defaultMetric {
+				defaultIface, defaultMetric = fields[0], metric
+			}
+			continue
+		}
+		routes = append(routes, hostRoute{iface: fields[0], dest: uint32(dest), mask: uint32(mask)})
+	}
+	return routes, defaultIface
+}
+
+// routeInterface returns the interface of the most specific route that
+// contains ip, which for a local address is its connected route.
+func routeInterface(routes []hostRoute, ip net.IP) string {
+	addr := binary.LittleEndian.Uint32(ip.To4())
+	best, bestMask := "", uint32(0)
+	for _, r := range routes {
+		if addr&r.mask == r.dest && (best == "" || r.mask > bestMask) {
+			best, bestMask = r.iface, r.mask
+		}
+	}
+	return best
+}
+
+// readLocalIPv4 returns the local IPv4 addresses from /proc/net/fib_trie,
+// where each address is followed by a "/32 host LOCAL" line.
+func readLocalIPv4() []net.IP {
+	f, err := os.Open(hostfs.NetProc("fib_trie"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	seen := make(map[string]bool)
+	var result []net.IP
+	var last string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if rest, ok := strings.CutPrefix(line, "|-- "); ok {
+			last = rest
+			continue
+		}
+		if strings.HasPrefix(line, "/32 host LOCAL") && last != "" && !seen[last] {
+			seen[last] = true
+			if ip := net.ParseIP(last); ip != nil && !ip.IsLoopback() {
+				result = append(result, ip)
+			}
+		}
+	}
+	return result
+}
+
+// readIPv6Addresses parses /proc/net/if_inet6, which has one line per
+// address: the address in hex, index, prefix length, scope, flags and name.
+func readIPv6Addresses() map[string][]string {
+	f, err := os.Open(hostfs.NetProc("if_inet6"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	result := make(map[string][]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+		raw, err := hex.DecodeString(fields[0])
+		if err != nil || len(raw) != net.IPv6len {
+			continue
+		}
+		result[fields[5]] = append(result[fields[5]], net.IP(raw).String())
+	}
+	return result
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package system
+
+// hostInterfaces is not needed on this platform: the agent never runs in a
+// separate network namespace here.
+func hostInterfaces() ([]ifaceInfo, string) {
+	return nil, ""
+}
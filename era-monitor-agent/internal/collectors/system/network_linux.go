@@ -0,0 +1,43 @@
+//go:build linux
+
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/eracloud/era-monitor-agent/internal/collectors/hostfs"
+)
+
+type linkInfo struct {
+	OperState string
+	SpeedMbps int
+	Duplex    string
+}
+
+// readLinkInfo reads link state, speed and duplex from /sys/class/net. Speed
+// and duplex are unavailable for virtual interfaces and links that are down.
+func readLinkInfo(name string) linkInfo {
+	dir := hostfs.Sys("class/net", name)
+	info := linkInfo{
+		OperState: readSysfsString(filepath.Join(dir, "operstate")),
+		Duplex:    readSysfsString(filepath.Join(dir, "duplex")),
+	}
+	if speed, err := strconv.Atoi(readSysfsString(filepath.Join(dir, "speed"))); err == nil && speed > 0 {
+		info.SpeedMbps = speed
+	}
+	if info.Duplex == "unknown" {
+		info.Duplex = ""
+	}
+	return info
+}
+
+func readSysfsString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package system
+
+type linkInfo struct {
+	OperState string
+	SpeedMbps int
+	Duplex    string
+}
+
+func readLinkInfo(name string) linkInfo {
+	return linkInfo{}
+}
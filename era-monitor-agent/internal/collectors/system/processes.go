@@ -0,0 +1,195 @@
+package system
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/eracloud/era-monitor-agent/internal/config"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// ProcessSnapshot is one entry of the top process lists. CPUPercent is the
+// share of one core used since the previous collection.
+type ProcessSnapshot struct {
+	PID        int32
+	Name       string
+	User       string
+	Cmdline    string
+	CPUPercent float64
+	RSSBytes   uint64
+}
+
+// TopProcesses lists the heaviest processes by CPU and by memory.
+type TopProcesses struct {
+	ByCPU    []ProcessSnapshot
+	ByMemory []ProcessSnapshot
+}
+
+type processSample struct {
+	created int64
+	cpu     float64
+}
+
+type processCandidate struct {
+	proc       *process.Process
+	cpuPercent float64
+	rss        uint64
+}
+
+// topProcessesCollector keeps per-process CPU times between cycles. CPU
+// times are sampled every cycle, even when the lists are not sent, so that
+// the percentages always cover the last interval.
+type topProcessesCollector struct {
+	cfg    config.TopProcessesConfig
+	redact []*regexp.Regexp
+
+	mu     sync.Mutex
+	last   map[int32]processSample
+	lastAt time.Time
+}
+
+func newTopProcessesCollector(cfg config.TopProcessesConfig) *topProcessesCollector {
+	t := &topProcessesCollector{cfg: cfg}
+	if t.cfg.Count <= 0 {
+		t.cfg.Count = 5
+	}
+	if t.cfg.CmdlineMaxLength <= 0 {
+		t.cfg.CmdlineMaxLength = 256
+	}
+	for _, pattern := range cfg.Redact {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			// config.Load rejects invalid patterns. Should one get here
+			// anyway, command lines are left out rather than sent
+			// unredacted.
+			t.cfg.IncludeCmdline = false
+			t.redact = nil
+			break
+		}
+		t.redact = append(t.redact, re)
+	}
+	return t
+}
+
+// Collect samples all processes and returns the top lists, or nil when the
+// configured mode is "threshold" and neither CPU nor RAM usage crossed it.
+func (t *topProcessesCollector) Collect(ctx context.Context, cpuPercent, ramPercent float64) *TopProcesses {
+	procs, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(t.lastAt).Seconds()
+	current := make(map[int32]processSample, len(procs))
+	candidates := make([]processCandidate, 0, len(procs))
+
+	for _, p := range procs {
+		c := processCandidate{proc: p}
+		created, _ := p.CreateTimeWithContext(ctx)
+		if times, err := p.TimesWithContext(ctx); err == nil {
+			total := times.User + times.System
+			current[p.Pid] = processSample{created: created, cpu: total}
+			// A PID reused by a new process has a different creation time.
+			if prev, ok := t.last[p.Pid]; ok && prev.created == created && elapsed > 0 && total >= prev.cpu {
+				c.cpuPercent = (total - prev.cpu) / elapsed * 100
+			}
+		}
+		if mem, err := p.MemoryInfoWithContext(ctx); err == nil {
+			c.rss = mem.RSS
+		}
+		candidates = append(candidates, c)
+	}
+
+	first := t.last == nil
+	t.last = current
+	t.lastAt = now
+
+	if t.cfg.Mode == "threshold" && cpuPercent < t.cfg.CPUThreshold && ramPercent < t.cfg.RAMThreshold {
+		return nil
+	}
+
+	result := &TopProcesses{}
+	// On the first cycle there is no CPU baseline yet.
+	if !first {
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].cpuPercent > candidates[j].cpuPercent })
+		result.ByCPU = t.snapshots(ctx, candidates)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].rss > candidates[j].rss })
+	result.ByMemory = t.snapshots(ctx, candidates)
+
+	return result
+}
+
+// snapshots describes the first Count candidates. Names, users and command
+// lines are only read for the processes that are reported.
+func (t *topProcessesCollector) snapshots(ctx context.Context, candidates []processCandidate) []ProcessSnapshot {
+	n := t.cfg.Count
+	if n > len(candidates) {
+		n = len(candidates)
+	}
+
+	result := make([]ProcessSnapshot, 0, n)
+	for _, c := range candidates[:n] {
+		s := ProcessSnapshot{
+			PID:        c.proc.Pid,
+			CPUPercent: c.cpuPercent,
+			RSSBytes:   c.rss,
+		}
+		s.Name, _ = c.proc.NameWithContext(ctx)
+		s.User, _ = c.proc.UsernameWithContext(ctx)
+		if t.cfg.IncludeCmdline {
+			if cmdline, err := c.proc.CmdlineWithContext(ctx); err == nil {
+				s.Cmdline = t.cleanCmdline(cmdline)
+			}
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+// cleanCmdline redacts secrets and truncates the command line. A match of a
+// redaction pattern is replaced with "***"; if the pattern has a capture
+// group, only the first group is replaced, so "(?:--password[= ])(\S+)"
+// keeps the flag name.
+func (t *topProcessesCollector) cleanCmdline(cmdline string) string {
+	for _, re := range t.redact {
+		cmdline = redact(re, cmdline)
+	}
+	if len(cmdline) > t.cfg.CmdlineMaxLength {
+		cut := t.cfg.CmdlineMaxLength
+		for cut > 0 && !utf8.RuneStart(cmdline[cut]) {
+			cut--
+		}
+		cmdline = cmdline[:cut] + "..."
+	}
+	return cmdline
+}
+
+func redact(re *regexp.Regexp, s string) string {
+	matches := re.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+
+	var out []byte
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		out = append(out, s[last:start]...)
+		out = append(out, "***"...)
+		last = end
+	}
+	out = append(out, s[last:]...)
+	return string(out)
+}
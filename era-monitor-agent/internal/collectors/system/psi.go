@@ -0,0 +1,30 @@
+package system
+
+// PSIMetrics holds Linux Pressure Stall Information. Available is false when
+// the kernel does not expose PSI; the other fields are then empty.
+type PSIMetrics struct {
+	Available bool
+	// Source is "proc" for system-wide figures or "cgroup" when read from the
+	// agent's own cgroup inside a container.
+	Source string
+	CPU    *PSIResource
+	Memory *PSIResource
+	IO     *PSIResource
+}
+
+// PSIResource holds the "some" and "full" lines of one pressure file. Full
+// is nil when the kernel does not report it for the resource.
+type PSIResource struct {
+	Some *PSILine
+	Full *PSILine
+}
+
+// PSILine holds the share of time tasks were stalled, averaged over 10, 60
+// and 300 seconds, and the stall time in microseconds since the previous
+// collection.
+type PSILine struct {
+	Avg10        float64
+	Avg60        float64
+	Avg300       float64
+	TotalDeltaUs uint64
+}
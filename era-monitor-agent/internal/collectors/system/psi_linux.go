@@ -0,0 +1,112 @@
+//go:build linux
+
+package system
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/eracloud/era-monitor-agent/internal/collectors/hostfs"
+)
+
+const cgroupPressureDir = "/sys/fs/cgroup"
+
+// collectPSI reads /proc/pressure, or the cgroup v2 pressure files when the
+// agent runs in a container, so that the figures describe what the agent
+// can actually use. In host mode the host's /proc/pressure is read instead.
+func (c *SystemCollector) collectPSI() *PSIMetrics {
+	source := "proc"
+	path := func(resource string) string {
+		return hostfs.Proc("pressure", resource)
+	}
+	if !hostfs.Enabled() && hostfs.InContainer() && fileExists(filepath.Join(cgroupPressureDir, "cpu.pressure")) {
+		source = "cgroup"
+		path = func(resource string) string {
+			return filepath.Join(cgroupPressureDir, resource+".pressure")
+		}
+	}
+
+	m := &PSIMetrics{
+		Source: source,
+		CPU:    c.readPressure("cpu", path("cpu")),
+		Memory: c.readPressure("memory", path("memory")),
+		IO:     c.readPressure("io", path("io")),
+	}
+	m.Available = m.CPU != nil || m.Memory != nil || m.IO != nil
+	if !m.Available {
+		return &PSIMetrics{}
+	}
+	return m
+}
+
+// readPressure parses one pressure file:
+//
+//	some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+func (c *SystemCollector) readPressure(resource, path string) *PSIResource {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	c.psiMu.Lock()
+	defer c.psiMu.Unlock()
+	if c.lastPSI == nil {
+		c.lastPSI = make(map[string]uint64)
+	}
+
+	r := &PSIResource{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		line := &PSILine{}
+		var total uint64
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			switch key {
+			case "avg10":
+				line.Avg10, _ = strconv.ParseFloat(value, 64)
+			case "avg60":
+				line.Avg60, _ = strconv.ParseFloat(value, 64)
+			case "avg300":
+				line.Avg300, _ = strconv.ParseFloat(value, 64)
+			case "total":
+				total, _ = strconv.ParseUint(value, 10, 64)
+			}
+		}
+
+		key := resource + "/" + fields[0]
+		if prev, ok := c.lastPSI[key]; ok && total >= prev {
+			line.TotalDeltaUs = total - prev
+		}
+		c.lastPSI[key] = total
+
+		switch fields[0] {
+		case "some":
+			r.Some = line
+		case "full":
+			r.Full = line
+		}
+	}
+
+	if r.Some == nil && r.Full == nil {
+		return nil
+	}
+	return r
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
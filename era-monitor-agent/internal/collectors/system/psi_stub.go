@@ -0,0 +1,8 @@
+//go:build !linux
+
+package system
+
+// collectPSI reports PSI as unavailable; it is a Linux kernel feature.
+func (c *SystemCollector) collectPSI() *PSIMetrics {
+	return &PSIMetrics{}
+}
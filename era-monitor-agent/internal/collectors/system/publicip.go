@@ -0,0 +1,365 @@
+package system
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eracloud/era-monitor-agent/internal/config"
+)
+
+// failureTTL bounds how long a failed lookup is cached, so that a transient
+// outage does not hide the public address for a whole TTL.
+const failureTTL = 5 * time.Minute
+
+const defaultPublicIPURL = "https://api.ipify.org"
+
+// PublicIPResult is the outcome of a public IP lookup.
+type PublicIPResult struct {
+	IPv4   string
+	IPv6   string
+	Source string
+}
+
+type publicIPProvider interface {
+	Name() string
+	// Resolve returns the public address seen for the given IP family,
+	// "4" or "6".
+	Resolve(ctx context.Context, family string) (net.IP, error)
+}
+
+// PublicIPResolver looks up the host's public address through a chain of
+// providers and caches the answer.
+type PublicIPResolver struct {
+	providers []publicIPProvider
+	ipv6      bool
+	ttl       time.Duration
+	timeout   time.Duration
+
+	mu        sync.Mutex
+	cached    *PublicIPResult
+	expiresAt time.Time
+}
+
+// NewPublicIPResolver builds a resolver from the configured providers.
+// Unknown provider types are skipped.
+func NewPublicIPResolver(cfg config.PublicIPConfig, server config.ServerConfig) *PublicIPResolver {
+	r := &PublicIPResolver{
+		ipv6:    cfg.IPv6,
+		ttl:     time.Duration(cfg.TTLSeconds) * time.Second,
+		timeout: time.Duration(cfg.TimeoutMs) * time.Millisecond,
+	}
+	if r.ttl <= 0 {
+		r.ttl = time.Hour
+	}
+	if r.timeout <= 0 {
+		r.timeout = 3 * time.Second
+	}
+
+	for _, p := range cfg.Providers {
+		switch strings.ToLower(p.Type) {
+		case "http":
+			r.providers = append(r.providers, newHTTPIPProvider("", p.Address, ""))
+		case "era":
+			address := p.Address
+			if address == "" {
+				address = strings.TrimSuffix(server.APIEndpoint, "/") + "/agent/public-ip"
+			}
+			r.providers = append(r.providers, newHTTPIPProvider("era", address, server.APIKey))
+		case "stun":
+			r.providers = append(r.providers, &stunIPProvider{server: p.Address})
+		}
+	}
+
+	// Without providers fall back to the lookup the agent always did.
+	if len(r.providers) == 0 {
+		r.providers = append(r.providers, newHTTPIPProvider("", defaultPublicIPURL, ""))
+	}
+
+	return r
+}
+
+// Resolve returns the cached public address, refreshing it when the TTL has
+// expired.
+func (r *PublicIPResolver) Resolve(ctx context.Context) PublicIPResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cached != nil && time.Now().Before(r.expiresAt) {
+		return *r.cached
+	}
+
+	result := PublicIPResult{}
+	if ip, source := r.resolveFamily(ctx, "4"); ip != nil {
+		result.IPv4 = ip.String()
+		result.Source = source
+	}
+	if r.ipv6 {
+		if ip, source := r.resolveFamily(ctx, "6"); ip != nil {
+			result.IPv6 = ip.String()
+			if result.Source == "" {
+				result.Source = source
+			}
+		}
+	}
+
+	ttl := r.ttl
+	if result.Source == "" && ttl > failureTTL {
+		ttl = failureTTL
+	}
+	r.cached = &result
+	r.expiresAt = time.Now().Add(ttl)
+
+	return result
+}
+
+func (r *PublicIPResolver) resolveFamily(ctx context.Context, family string) (net.IP, string) {
+	for _, p := range r.providers {
+		pctx, cancel := context.WithTimeout(ctx, r.timeout)
+		ip, err := p.Resolve(pctx, family)
+		cancel()
+		if err == nil && ip != nil {
+			return ip, p.Name()
+		}
+	}
+	return nil, ""
+}
+
+// matchesFamily reports whether ip belongs to the requested family.
+func matchesFamily(ip net.IP, family string) bool {
+	if family == "6" {
+		return ip.To4() == nil
+	}
+	return ip.To4() != nil
+}
+
+// httpIPProvider queries an echo service that answers with the caller's
+// address, either as plain text or as JSON with an "ip" field.
+type httpIPProvider struct {
+	name   string
+	url    string
+	apiKey string
+	// clients holds one client per IP family, built once so that lookups
+	// do not leak a transport each.
+	clients map[string]*http.Client
+}
+
+func newHTTPIPProvider(name, url, apiKey string) *httpIPProvider {
+	p := &httpIPProvider{
+		name:    name,
+		url:     url,
+		apiKey:  apiKey,
+		clients: make(map[string]*http.Client, 2),
+	}
+	for _, family := range []string{"4", "6"} {
+		network := "tcp" + family
+		dialer := &net.Dialer{}
+		p.clients[family] = &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+					return dialer.DialContext(ctx, network, addr)
+				},
+				// Lookups are a TTL apart, so idle connections would only
+				// be held open for nothing.
+				DisableKeepAlives: true,
+			},
+		}
+	}
+	return p
+}
+
+func (p *httpIPProvider) Name() string {
+	if p.name != "" {
+		return p.name
+	}
+	return "http:" + p.url
+}
+
+func (p *httpIPProvider) Resolve(ctx context.Context, family string) (net.IP, error) {
+	client := p.clients[family]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if p.apiKey != "" {
+		req.Header.Set("X-API-Key", p.apiKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("public IP provider returned %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return nil, err
+	}
+
+	text := strings.TrimSpace(string(body))
+	var payload struct {
+		IP string `json:"ip"`
+	}
+	if json.Unmarshal(body, &payload) == nil && payload.IP != "" {
+		text = payload.IP
+	}
+
+	ip := net.ParseIP(text)
+	if ip == nil || !matchesFamily(ip, family) {
+		return nil, errors.New("public IP provider returned no usable address")
+	}
+	return ip, nil
+}
+
+// stunIPProvider sends a STUN binding request (RFC 5389) and reads the
+// mapped address from the response.
+type stunIPProvider struct {
+	server string
+}
+
+const (
+	stunMagicCookie       = 0x2112A442
+	stunBindingRequest    = 0x0001
+	stunBindingSuccess    = 0x0101
+	stunAttrMappedAddress = 0x0001
+	stunAttrXorMapped     = 0x0020
+)
+
+func (p *stunIPProvider) Name() string {
+	return "stun:" + p.server
+}
+
+func (p *stunIPProvider) Resolve(ctx context.Context, family string) (net.IP, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp"+family, p.server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	req := make([]byte, 20)
+	binary.BigEndian.PutUint16(req[0:2], stunBindingRequest)
+	binary.BigEndian.PutUint16(req[2:4], 0)
+	binary.BigEndian.PutUint32(req[4:8], stunMagicCookie)
+	txID := req[8:20]
+	if _, err := rand.Read(txID); err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 1500)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	ip, err := parseSTUNResponse(buf[:n], txID)
+	if err != nil {
+		return nil, err
+	}
+	if !matchesFamily(ip, family) {
+		return nil, errors.New("stun server returned an address of the wrong family")
+	}
+	return ip, nil
+}
+
+func parseSTUNResponse(msg, txID []byte) (net.IP, error) {
+	if len(msg) < 20 {
+		return nil, errors.New("stun response too short")
+	}
+	if binary.BigEndian.Uint16(msg[0:2]) != stunBindingSuccess {
+		return nil, errors.New("stun response is not a binding success")
+	}
+	if binary.BigEndian.Uint32(msg[4:8]) != stunMagicCookie || string(msg[8:20]) != string(txID) {
+		return nil, errors.New("stun response does not match request")
+	}
+
+	length := int(binary.BigEndian.Uint16(msg[2:4]))
+	if 20+length > len(msg) {
+		return nil, errors.New("stun response truncated")
+	}
+	attrs := msg[20 : 20+length]
+
+	var mapped net.IP
+	for len(attrs) >= 4 {
+		attrType := binary.BigEndian.Uint16(attrs[0:2])
+		attrLen := int(binary.BigEndian.Uint16(attrs[2:4]))
+		if 4+attrLen > len(attrs) {
+			break
+		}
+		value := attrs[4 : 4+attrLen]
+
+		switch attrType {
+		case stunAttrXorMapped:
+			if ip := decodeSTUNAddress(value, msg[4:20]); ip != nil {
+				return ip, nil
+			}
+		case stunAttrMappedAddress:
+			mapped = decodeSTUNAddress(value, nil)
+		}
+
+		// Attributes are padded to a multiple of four bytes.
+		next := 4 + (attrLen+3)&^3
+		if next > len(attrs) {
+			break
+		}
+		attrs = attrs[next:]
+	}
+
+	if mapped != nil {
+		return mapped, nil
+	}
+	return nil, errors.New("stun response has no mapped address")
+}
+
+// decodeSTUNAddress decodes a (XOR-)MAPPED-ADDRESS value. xorKey is the magic
+// cookie followed by the transaction ID, or nil for a plain MAPPED-ADDRESS.
+func decodeSTUNAddress(value, xorKey []byte) net.IP {
+	if len(value) < 4 {
+		return nil
+	}
+
+	var size int
+	switch value[1] {
+	case 0x01:
+		size = net.IPv4len
+	case 0x02:
+		size = net.IPv6len
+	default:
+		return nil
+	}
+	if len(value) < 4+size {
+		return nil
+	}
+
+	ip := make(net.IP, size)
+	copy(ip, value[4:4+size])
+	if xorKey != nil {
+		for i := range ip {
+			ip[i] ^= xorKey[i]
+		}
+	}
+	return ip
+}
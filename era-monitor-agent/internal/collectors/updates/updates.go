@@ -0,0 +1,147 @@
+// Package updates reports pending operating system updates and whether the
+// host needs a reboot, from the package manager caches as they are. The
+// caches are never refreshed, so the counts are as current as the host's
+// own update timer keeps them.
+package updates
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+	"github.com/eracloud/era-monitor-agent/internal/config"
+)
+
+// Update status values, from most to least urgent.
+const (
+	StatusRebootRequired   = "reboot-required"
+	StatusSecurityUpdates  = "security-updates"
+	StatusUpdatesAvailable = "updates-available"
+	StatusOK               = "ok"
+)
+
+// Reasons given in api.UpdateStatus.RebootReasons.
+const (
+	ReasonRebootRequiredFile = "reboot-required-file"
+	ReasonNewerKernel        = "newer-kernel"
+)
+
+const commandTimeout = 60 * time.Second
+
+// Collector checks for updates once per refresh interval and caches the
+// result in between; querying the package manager takes seconds.
+type Collector struct {
+	refresh time.Duration
+
+	mu        sync.Mutex
+	status    *api.UpdateStatus
+	checkedAt time.Time
+}
+
+// NewCollector creates an update collector
+func NewCollector(cfg config.UpdatesConfig) *Collector {
+	refresh := time.Duration(cfg.RefreshMinutes) * time.Minute
+	if refresh <= 0 {
+		refresh = time.Hour
+	}
+	return &Collector{refresh: refresh}
+}
+
+// Collect returns the update status, or nil when the platform has no
+// supported package manager.
+func (c *Collector) Collect(ctx context.Context) *api.UpdateStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.checkedAt.IsZero() && time.Since(c.checkedAt) < c.refresh {
+		return c.status
+	}
+
+	c.status = check(ctx)
+	if c.status != nil {
+		c.status.Status = summarize(c.status)
+		c.status.CheckedAt = time.Now().UTC()
+	}
+	c.checkedAt = time.Now()
+	return c.status
+}
+
+func summarize(s *api.UpdateStatus) string {
+	switch {
+	case s.RebootRequired:
+		return StatusRebootRequired
+	case s.SecurityUpdates > 0:
+		return StatusSecurityUpdates
+	case s.PendingUpdates > 0:
+		return StatusUpdatesAvailable
+	default:
+		return StatusOK
+	}
+}
+
+// compareVersions compares kernel release strings such as "6.1.0-13-amd64"
+// or "5.14.0-362.8.1.el9_3.x86_64" segment by segment, numbers numerically
+// and everything else as text.
+func compareVersions(a, b string) int {
+	as, bs := segments(a), segments(b)
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				if an < bn {
+					return -1
+				}
+				return 1
+			}
+		case as[i] != bs[i]:
+			return strings.Compare(as[i], bs[i])
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return 0
+}
+
+// segments splits a version into runs of digits and runs of letters,
+// dropping separators.
+func segments(v string) []string {
+	var result []string
+	var current strings.Builder
+	digits := false
+	flush := func() {
+		if current.Len() > 0 {
+			result = append(result, current.String())
+			current.Reset()
+		}
+	}
+	for _, r := range v {
+		switch {
+		case unicode.IsDigit(r):
+			if !digits {
+				flush()
+			}
+			digits = true
+			current.WriteRune(r)
+		case unicode.IsLetter(r):
+			if digits {
+				flush()
+			}
+			digits = false
+			current.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return result
+}
@@ -0,0 +1,203 @@
+//go:build linux
+
+package updates
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+	"github.com/eracloud/era-monitor-agent/internal/collectors/hostfs"
+	"golang.org/x/sys/unix"
+)
+
+func check(ctx context.Context) *api.UpdateStatus {
+	s := &api.UpdateStatus{}
+
+	switch {
+	case hasCommand("apt-get"):
+		s.PackageManager = "apt"
+		checkApt(ctx, s)
+	case hasCommand("dnf"):
+		s.PackageManager = "dnf"
+		checkDnf(ctx, "dnf", s)
+	case hasCommand("yum"):
+		s.PackageManager = "yum"
+		checkDnf(ctx, "yum", s)
+	default:
+		return nil
+	}
+
+	// Debian and Ubuntu packages touch this file when they need a reboot.
+	if fileExists(hostfs.Root("/var/run/reboot-required")) {
+		s.RebootRequired = true
+		s.RebootReasons = append(s.RebootReasons, ReasonRebootRequiredFile)
+		s.RebootPackages = readLines(hostfs.Root("/var/run/reboot-required.pkgs"))
+	}
+
+	s.RunningKernel = runningKernel()
+	s.LatestKernel = latestKernel()
+	if s.RunningKernel != "" && s.LatestKernel != "" && compareVersions(s.LatestKernel, s.RunningKernel) > 0 {
+		s.RebootRequired = true
+		s.RebootReasons = append(s.RebootReasons, ReasonNewerKernel)
+	}
+
+	return s
+}
+
+// checkApt simulates an upgrade against the current package lists. Lines of
+// the form "Inst pkg [old] (new origin [arch])" are the pending upgrades;
+// the origin names the security archive for security fixes.
+func checkApt(ctx context.Context, s *api.UpdateStatus) {
+	args := []string{"-s", "-o", "Debug::NoLocking=1"}
+	if hostfs.Enabled() {
+		root := hostfs.Root("/")
+		args = append(args,
+			"-o", "Dir="+root,
+			"-o", "Dir::State::status="+filepath.Join(root, "var/lib/dpkg/status"))
+	}
+	args = append(args, "dist-upgrade")
+
+	out, err := run(ctx, "apt-get", args...)
+	if err != nil {
+		s.Error = err.Error()
+		return
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "Inst ") {
+			continue
+		}
+		s.PendingUpdates++
+		if strings.Contains(strings.ToLower(line), "-security") {
+			s.SecurityUpdates++
+		}
+	}
+
+	if info, err := os.Stat(hostfs.Root("/var/lib/apt/lists")); err == nil {
+		t := info.ModTime().UTC()
+		s.MetadataUpdatedAt = &t
+	}
+}
+
+// checkDnf lists available updates from the metadata cache only (-C). Both
+// commands print one package per line after an optional header.
+func checkDnf(ctx context.Context, command string, s *api.UpdateStatus) {
+	base := []string{"-C", "-q"}
+	if hostfs.Enabled() {
+		base = append(base, "--installroot", hostfs.Root("/"))
+	}
+
+	// check-update exits with 100 when updates are available.
+	out, err := run(ctx, command, append(base, "check-update")...)
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 100) {
+		s.Error = err.Error()
+		return
+	}
+	s.PendingUpdates = countPackageLines(out)
+
+	if out, err := run(ctx, command, append(base, "updateinfo", "list", "--security")...); err == nil {
+		s.SecurityUpdates = countPackageLines(out)
+	}
+
+	if info, err := os.Stat(hostfs.Root("/var/cache/" + command)); err == nil {
+		t := info.ModTime().UTC()
+		s.MetadataUpdatedAt = &t
+	}
+}
+
+// countPackageLines counts lines with at least three columns, skipping the
+// "Obsoleting Packages" section of check-update.
+func countPackageLines(out []byte) int {
+	n := 0
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "Obsoleting") {
+			break
+		}
+		if len(strings.Fields(line)) >= 3 && !strings.HasPrefix(line, " ") {
+			n++
+		}
+	}
+	return n
+}
+
+func runningKernel() string {
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err != nil {
+		return ""
+	}
+	return unix.ByteSliceToString(uts.Release[:])
+}
+
+// latestKernel returns the newest kernel installed in /boot, or in
+// /lib/modules on systems that keep the kernel elsewhere.
+func latestKernel() string {
+	var versions []string
+	if matches, _ := filepath.Glob(hostfs.Root("/boot/vmlinuz-*")); len(matches) > 0 {
+		for _, m := range matches {
+			versions = append(versions, strings.TrimPrefix(filepath.Base(m), "vmlinuz-"))
+		}
+	} else if entries, err := os.ReadDir(hostfs.Root("/lib/modules")); err == nil {
+		for _, e := range entries {
+			if e.IsDir() {
+				versions = append(versions, e.Name())
+			}
+		}
+	}
+
+	latest := ""
+	for _, v := range versions {
+		// Rescue images are not kernels anyone boots into on purpose.
+		if strings.Contains(v, "rescue") {
+			continue
+		}
+		if latest == "" || compareVersions(v, latest) > 0 {
+			latest = v
+		}
+	}
+	return latest
+}
+
+func run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	return cmd.Output()
+}
+
+func hasCommand(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func readLines(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
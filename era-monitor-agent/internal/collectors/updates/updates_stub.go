@@ -0,0 +1,14 @@
+//go:build !linux
+
+package updates
+
+import (
+	"context"
+
+	"github.com/eracloud/era-monitor-agent/internal/api"
+)
+
+// check is not supported on this platform.
+func check(ctx context.Context) *api.UpdateStatus {
+	return nil
+}
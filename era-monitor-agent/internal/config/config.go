@@ -1,10 +1,16 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
 
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
 )
 
@@ -12,6 +18,7 @@ type Config struct {
 	Server     ServerConfig     `mapstructure:"server"`
 	Host       HostConfig       `mapstructure:"host"`
 	Collectors CollectorsConfig `mapstructure:"collectors"`
+	Heartbeat  HeartbeatConfig  `mapstructure:"heartbeat"`
 	Services   ServicesConfig   `mapstructure:"services"`
 	GUI        GUIConfig        `mapstructure:"gui"`
 	Agent      AgentConfig      `mapstructure:"agent"`
@@ -27,30 +34,210 @@ type ServerConfig struct {
 }
 
 type HostConfig struct {
-	DisplayName string   `mapstructure:"displayName"`
-	Location    string   `mapstructure:"location"`
-	Tags        []string `mapstructure:"tags"`
+	DisplayName string            `mapstructure:"displayName"`
+	Location    string            `mapstructure:"location"`
+	Tags        []string          `mapstructure:"tags"`
+	Attributes  map[string]string `mapstructure:"attributes"`
+
+	// Dynamic attributes. Every file in AttributesDir and the output of every
+	// command in AttributeCommands is read as key=value lines.
+	AttributesDir            string   `mapstructure:"attributesDir"`
+	AttributeCommands        []string `mapstructure:"attributeCommands"`
+	AttributesRefreshSeconds int      `mapstructure:"attributesRefreshSeconds"`
 }
 
 type CollectorsConfig struct {
 	IntervalSeconds int                   `mapstructure:"intervalSeconds"`
 	System          SystemCollectorConfig `mapstructure:"system"`
+	Cloud           CloudCollectorConfig  `mapstructure:"cloud"`
+	Inventory       InventoryConfig       `mapstructure:"inventory"`
+	Packages        PackagesConfig        `mapstructure:"packages"`
+	Updates         UpdatesConfig         `mapstructure:"updates"`
+	Events          EventsConfig          `mapstructure:"events"`
+}
+
+// InventoryConfig controls the hardware and OS inventory. It is re-read every
+// RefreshMinutes and only sent when it changed.
+type InventoryConfig struct {
+	Enabled        bool `mapstructure:"enabled"`
+	RefreshMinutes int  `mapstructure:"refreshMinutes"`
+}
+
+// PackagesConfig controls the installed package inventory from dpkg and rpm.
+// The package databases are re-read every RefreshMinutes.
+type PackagesConfig struct {
+	Enabled        bool `mapstructure:"enabled"`
+	RefreshMinutes int  `mapstructure:"refreshMinutes"`
+}
+
+// EventsConfig controls the event pipeline. BookmarkPath stores where each
+// event source was last read. At most RateLimitPerMinute events of the same
+// log, source and event ID are sent per minute; the rest are summarised.
+type EventsConfig struct {
+	BookmarkPath       string `mapstructure:"bookmarkPath"`
+	RateLimitPerMinute int    `mapstructure:"rateLimitPerMinute"`
+}
+
+// UpdatesConfig controls the pending update and reboot-required check, which
+// is repeated every RefreshMinutes.
+type UpdatesConfig struct {
+	Enabled        bool `mapstructure:"enabled"`
+	RefreshMinutes int  `mapstructure:"refreshMinutes"`
+}
+
+type CloudCollectorConfig struct {
+	Enabled        bool     `mapstructure:"enabled"`
+	Providers      []string `mapstructure:"providers"`
+	TimeoutMs      int      `mapstructure:"timeoutMs"`
+	RefreshMinutes int      `mapstructure:"refreshMinutes"`
+	IncludeTags    bool     `mapstructure:"includeTags"`
 }
 
 type SystemCollectorConfig struct {
-	Enabled  bool `mapstructure:"enabled"`
-	CPU      bool `mapstructure:"cpu"`
-	RAM      bool `mapstructure:"ram"`
-	Disk     bool `mapstructure:"disk"`
-	Network  bool `mapstructure:"network"`
-	EventLog bool `mapstructure:"eventLog"`
+	Enabled  bool           `mapstructure:"enabled"`
+	CPU      bool           `mapstructure:"cpu"`
+	RAM      bool           `mapstructure:"ram"`
+	Disk     bool           `mapstructure:"disk"`
+	Network  bool           `mapstructure:"network"`
+	EventLog bool           `mapstructure:"eventLog"`
+	PSI      bool           `mapstructure:"psi"`
+	Kernel   bool           `mapstructure:"kernel"`
+	PublicIP PublicIPConfig `mapstructure:"publicIp"`
+
+	// InterfaceExclude lists glob patterns of network interfaces to leave out
+	// of the interface metrics and of the hardware inventory.
+	InterfaceExclude []string `mapstructure:"interfaceExclude"`
+
+	DiskFilter DiskFilterConfig `mapstructure:"diskFilter"`
+
+	TopProcesses TopProcessesConfig `mapstructure:"topProcesses"`
+
+	HostMode HostModeConfig `mapstructure:"hostMode"`
+}
+
+// HostModeConfig lets an agent running in a container monitor the host
+// through mounted host paths. The HOST_PROC, HOST_SYS, HOST_ETC and HOST_ROOT
+// environment variables take precedence over the paths given here.
+type HostModeConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Proc    string `mapstructure:"proc"`
+	Sys     string `mapstructure:"sys"`
+	Etc     string `mapstructure:"etc"`
+	Root    string `mapstructure:"root"`
+}
+
+// TopProcessesConfig controls the lists of the heaviest processes by CPU and
+// by memory. Mode "always" sends them every cycle; "threshold" only when CPU
+// or RAM usage reaches the given percentage. Each Redact pattern hides the
+// matching part of a command line, or only its first capture group if it has
+// one.
+type TopProcessesConfig struct {
+	Enabled          bool     `mapstructure:"enabled"`
+	Count            int      `mapstructure:"count"`
+	Mode             string   `mapstructure:"mode"`
+	CPUThreshold     float64  `mapstructure:"cpuThreshold"`
+	RAMThreshold     float64  `mapstructure:"ramThreshold"`
+	IncludeCmdline   bool     `mapstructure:"includeCmdline"`
+	CmdlineMaxLength int      `mapstructure:"cmdlineMaxLength"`
+	Redact           []string `mapstructure:"redact"`
+}
+
+// DiskFilterConfig selects the filesystems the disk collector reports. All
+// entries are glob patterns; a mountpoint pattern also matches everything
+// below a matching directory. Include lists, when set, are applied before the
+// exclude lists.
+type DiskFilterConfig struct {
+	IncludeFsTypes     []string `mapstructure:"includeFsTypes"`
+	ExcludeFsTypes     []string `mapstructure:"excludeFsTypes"`
+	IncludeMountPoints []string `mapstructure:"includeMountPoints"`
+	ExcludeMountPoints []string `mapstructure:"excludeMountPoints"`
+	ExcludeDevices     []string `mapstructure:"excludeDevices"`
+
+	// StatTimeoutMs bounds how long a network filesystem may take to report
+	// its usage before it is marked stale.
+	StatTimeoutMs int `mapstructure:"statTimeoutMs"`
+}
+
+// PublicIPConfig controls how the public address is resolved. Providers are
+// tried in order until one answers.
+type PublicIPConfig struct {
+	Enabled    bool                     `mapstructure:"enabled"`
+	IPv6       bool                     `mapstructure:"ipv6"`
+	TTLSeconds int                      `mapstructure:"ttlSeconds"`
+	TimeoutMs  int                      `mapstructure:"timeoutMs"`
+	Providers  []PublicIPProviderConfig `mapstructure:"providers"`
+}
+
+// PublicIPProviderConfig describes one public IP provider. Type is "http"
+// (an echo service at Address), "era" (the ERA API) or "stun" (a STUN server
+// at Address, host:port).
+type PublicIPProviderConfig struct {
+	Type    string `mapstructure:"type"`
+	Address string `mapstructure:"address"`
+}
+
+type HeartbeatConfig struct {
+	DeltaEnabled      bool          `mapstructure:"deltaEnabled"`
+	FullSnapshotEvery int           `mapstructure:"fullSnapshotEvery"`
+	Streams           StreamsConfig `mapstructure:"streams"`
+}
+
+// StreamsConfig splits the heartbeat into independent uploads so that a
+// backlog on one stream never delays the others.
+type StreamsConfig struct {
+	Enabled   bool         `mapstructure:"enabled"`
+	Metrics   StreamConfig `mapstructure:"metrics"`
+	Inventory StreamConfig `mapstructure:"inventory"`
+	Events    StreamConfig `mapstructure:"events"`
+}
+
+type StreamConfig struct {
+	Path       string `mapstructure:"path"`
+	QueueSize  int    `mapstructure:"queueSize"`
+	MaxBatch   int    `mapstructure:"maxBatch"`
+	MaxBytes   int    `mapstructure:"maxBytes"`
+	RetryCount int    `mapstructure:"retryCount"`
+	RetryDelay int    `mapstructure:"retryDelay"`
+}
+
+// DefaultStreamsConfig returns the stream settings used when none are configured.
+func DefaultStreamsConfig() StreamsConfig {
+	return StreamsConfig{
+		Enabled: false,
+		Metrics: StreamConfig{
+			Path:       "/agent/heartbeat",
+			QueueSize:  5,
+			MaxBatch:   1,
+			MaxBytes:   256 * 1024,
+			RetryCount: 1,
+			RetryDelay: 2,
+		},
+		Inventory: StreamConfig{
+			Path:       "/agent/inventory",
+			QueueSize:  1,
+			MaxBatch:   1,
+			MaxBytes:   4 * 1024 * 1024,
+			RetryCount: 3,
+			RetryDelay: 5,
+		},
+		Events: StreamConfig{
+			Path:       "/agent/events",
+			QueueSize:  5000,
+			MaxBatch:   200,
+			MaxBytes:   512 * 1024,
+			RetryCount: 3,
+			RetryDelay: 10,
+		},
+	}
 }
 
 type ServicesConfig struct {
-	Windows WindowsServicesConfig `mapstructure:"windows"`
-	Systemd SystemdServicesConfig `mapstructure:"systemd"`
-	Docker  DockerServicesConfig  `mapstructure:"docker"`
-	IIS     IISServicesConfig     `mapstructure:"iis"`
+	Windows   WindowsServicesConfig `mapstructure:"windows"`
+	Systemd   SystemdServicesConfig `mapstructure:"systemd"`
+	Docker    DockerServicesConfig  `mapstructure:"docker"`
+	IIS       IISServicesConfig     `mapstructure:"iis"`
+	Mounts    MountServicesConfig   `mapstructure:"mounts"`
+	Processes ProcessServicesConfig `mapstructure:"processes"`
 }
 
 type WindowsServicesConfig struct {
@@ -61,11 +248,15 @@ type WindowsServicesConfig struct {
 type SystemdServicesConfig struct {
 	Enabled bool     `mapstructure:"enabled"`
 	Units   []string `mapstructure:"units"`
+	// Cgroups lists services, slices and scopes whose cgroup limits and
+	// usage are reported, e.g. "nginx.service" or "user.slice".
+	Cgroups []string `mapstructure:"cgroups"`
 }
 
 type DockerServicesConfig struct {
 	Enabled    bool     `mapstructure:"enabled"`
 	Containers []string `mapstructure:"containers"`
+	Cgroups    bool     `mapstructure:"cgroups"`
 }
 
 type IISServicesConfig struct {
@@ -74,6 +265,45 @@ type IISServicesConfig struct {
 	AppPools []string `mapstructure:"appPools"`
 }
 
+// MountServicesConfig lists mounts that must be present, such as backup
+// targets on NFS or SMB shares. Each one is reported as a service.
+type MountServicesConfig struct {
+	Enabled bool                  `mapstructure:"enabled"`
+	Mounts  []ExpectedMountConfig `mapstructure:"mounts"`
+	// TimeoutMs bounds how long a mount may take to answer before it is
+	// reported as unreachable.
+	TimeoutMs int `mapstructure:"timeoutMs"`
+}
+
+// ExpectedMountConfig describes one expected mount. FsType and Source are
+// optional; Source may be a glob pattern such as "nas01:/backup*".
+type ExpectedMountConfig struct {
+	Path      string  `mapstructure:"path"`
+	FsType    string  `mapstructure:"fsType"`
+	Source    string  `mapstructure:"source"`
+	MinFreeGB float64 `mapstructure:"minFreeGb"`
+}
+
+// ProcessServicesConfig lists process groups to watch, for applications that
+// run neither as a system service nor in a container.
+type ProcessServicesConfig struct {
+	Enabled   bool                 `mapstructure:"enabled"`
+	Processes []ProcessMatchConfig `mapstructure:"processes"`
+}
+
+// ProcessMatchConfig selects the processes of one group. All set criteria
+// must match; when none is set, the process name must equal Name. ProcessName
+// and Exe are glob patterns, and an Exe pattern without a path separator is
+// matched against the file name only. Cmdline is a regular expression.
+type ProcessMatchConfig struct {
+	Name         string `mapstructure:"name"`
+	ProcessName  string `mapstructure:"processName"`
+	Exe          string `mapstructure:"exe"`
+	Cmdline      string `mapstructure:"cmdline"`
+	User         string `mapstructure:"user"`
+	MinInstances int    `mapstructure:"minInstances"`
+}
+
 type GUIConfig struct {
 	Enabled           bool `mapstructure:"enabled"`
 	StartMinimized    bool `mapstructure:"startMinimized"`
@@ -81,10 +311,21 @@ type GUIConfig struct {
 }
 
 type AgentConfig struct {
-	RunAsService    bool `mapstructure:"runAsService"`
-	StartWithOS     bool `mapstructure:"startWithOS"`
-	MinimizeToTray  bool `mapstructure:"minimizeToTray"`
-	CheckForUpdates bool `mapstructure:"checkForUpdates"`
+	RunAsService    bool           `mapstructure:"runAsService"`
+	StartWithOS     bool           `mapstructure:"startWithOS"`
+	MinimizeToTray  bool           `mapstructure:"minimizeToTray"`
+	CheckForUpdates bool           `mapstructure:"checkForUpdates"`
+	Shutdown        ShutdownConfig `mapstructure:"shutdown"`
+}
+
+// ShutdownConfig controls the final message sent when the agent is stopped
+// on purpose, so the server can tell a planned stop from a crash.
+type ShutdownConfig struct {
+	Notify                bool   `mapstructure:"notify"`
+	TimeoutSeconds        int    `mapstructure:"timeoutSeconds"`
+	RebootDowntimeSeconds int    `mapstructure:"rebootDowntimeSeconds"`
+	UpdateDowntimeSeconds int    `mapstructure:"updateDowntimeSeconds"`
+	UpdateMarkerPath      string `mapstructure:"updateMarkerPath"`
 }
 
 type LoggingConfig struct {
@@ -105,24 +346,95 @@ func GetDefaultConfig() *Config {
 			RetryDelay:  5,
 		},
 		Host: HostConfig{
-			DisplayName: getHostname(),
+			DisplayName:              getHostname(),
+			AttributesRefreshSeconds: 300,
 		},
 		Collectors: CollectorsConfig{
 			IntervalSeconds: 60,
 			System: SystemCollectorConfig{
-				Enabled:  true,
-				CPU:      true,
-				RAM:      true,
-				Disk:     true,
-				Network:  true,
-				EventLog: true,
+				Enabled:          true,
+				CPU:              true,
+				RAM:              true,
+				Disk:             true,
+				Network:          true,
+				EventLog:         true,
+				PSI:              true,
+				Kernel:           true,
+				InterfaceExclude: []string{"veth*"},
+				DiskFilter: DiskFilterConfig{
+					ExcludeFsTypes: []string{
+						"squashfs", "overlay", "tmpfs", "devtmpfs", "nsfs", "tracefs",
+						"proc", "sysfs", "cgroup", "cgroup2", "devpts", "mqueue", "debugfs",
+						"securityfs", "pstore", "bpf", "configfs", "fusectl", "hugetlbfs",
+						"autofs", "binfmt_misc", "rpc_pipefs", "efivarfs", "selinuxfs", "ramfs",
+						"fuse.lxcfs", "fuse.portal", "fuse.gvfsd-fuse",
+					},
+					ExcludeMountPoints: []string{"/snap/*", "/var/lib/docker/*", "/var/lib/containers/*"},
+					StatTimeoutMs:      2000,
+				},
+				TopProcesses: TopProcessesConfig{
+					Enabled:          true,
+					Count:            5,
+					Mode:             "threshold",
+					CPUThreshold:     80,
+					RAMThreshold:     90,
+					IncludeCmdline:   true,
+					CmdlineMaxLength: 256,
+					Redact: []string{
+						`(?i)(?:password|passwd|pwd|secret|token|api[-_]?key)(?:=|:|\s+)(\S+)`,
+						`://[^:/\s]+:([^@/\s]+)@`,
+					},
+				},
+				HostMode: HostModeConfig{
+					Proc: "/host/proc",
+					Sys:  "/host/sys",
+					Etc:  "/host/etc",
+					Root: "/host",
+				},
+				PublicIP: PublicIPConfig{
+					Enabled:    true,
+					TTLSeconds: 3600,
+					TimeoutMs:  3000,
+					Providers: []PublicIPProviderConfig{
+						{Type: "http", Address: "https://api.ipify.org"},
+					},
+				},
+			},
+			Cloud: CloudCollectorConfig{
+				Enabled:        true,
+				TimeoutMs:      500,
+				RefreshMinutes: 60,
+				IncludeTags:    true,
+			},
+			Inventory: InventoryConfig{
+				Enabled:        true,
+				RefreshMinutes: 15,
+			},
+			Packages: PackagesConfig{
+				Enabled:        true,
+				RefreshMinutes: 60,
+			},
+			Updates: UpdatesConfig{
+				Enabled:        true,
+				RefreshMinutes: 60,
+			},
+			Events: EventsConfig{
+				BookmarkPath:       DefaultEventBookmarkPath(),
+				RateLimitPerMinute: 20,
 			},
 		},
+		Heartbeat: HeartbeatConfig{
+			DeltaEnabled:      false,
+			FullSnapshotEvery: 60,
+			Streams:           DefaultStreamsConfig(),
+		},
 		Services: ServicesConfig{
-			Windows: WindowsServicesConfig{Enabled: runtime.GOOS == "windows"},
-			Systemd: SystemdServicesConfig{Enabled: runtime.GOOS == "linux"},
-			Docker:  DockerServicesConfig{Enabled: true},
-			IIS:     IISServicesConfig{Enabled: runtime.GOOS == "windows"},
+			Windows:   WindowsServicesConfig{Enabled: runtime.GOOS == "windows"},
+			Systemd:   SystemdServicesConfig{Enabled: runtime.GOOS == "linux"},
+			Docker:    DockerServicesConfig{Enabled: true, Cgroups: true},
+			IIS:       IISServicesConfig{Enabled: runtime.GOOS == "windows"},
+			Mounts:    MountServicesConfig{Enabled: true, TimeoutMs: 2000},
+			Processes: ProcessServicesConfig{Enabled: true},
 		},
 		GUI: GUIConfig{
 			Enabled:           runtime.GOOS == "windows",
@@ -134,6 +446,13 @@ func GetDefaultConfig() *Config {
 			StartWithOS:     true,
 			MinimizeToTray:  true,
 			CheckForUpdates: true,
+			Shutdown: ShutdownConfig{
+				Notify:                true,
+				TimeoutSeconds:        5,
+				RebootDowntimeSeconds: 300,
+				UpdateDowntimeSeconds: 60,
+				UpdateMarkerPath:      getDefaultUpdateMarkerPath(),
+			},
 		},
 		Logging: LoggingConfig{
 			Level:      "info",
@@ -158,6 +477,22 @@ func getDefaultLogPath() string {
 	return "/var/log/era-monitor/agent.log"
 }
 
+// DefaultEventBookmarkPath returns the platform's location for the event
+// bookmarks file.
+func DefaultEventBookmarkPath() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("ProgramData"), "ERAMonitor", "event-bookmarks.json")
+	}
+	return "/var/lib/era-monitor/event-bookmarks.json"
+}
+
+func getDefaultUpdateMarkerPath() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("ProgramData"), "ERAMonitor", "updating")
+	}
+	return "/var/lib/era-monitor/updating"
+}
+
 func Load(path string) (*Config, error) {
 	v := viper.New()
 	v.SetConfigFile(path)
@@ -177,13 +512,41 @@ func Load(path string) (*Config, error) {
 		return defaultCfg, nil
 	}
 
-	if err := v.Unmarshal(defaultCfg); err != nil {
+	// Lists and maps in the file replace the defaults rather than being
+	// merged into them element by element.
+	if err := v.Unmarshal(defaultCfg, func(dc *mapstructure.DecoderConfig) {
+		dc.ZeroFields = true
+	}); err != nil {
 		return nil, err
 	}
 
+	if err := defaultCfg.validate(); err != nil {
+		return nil, err
+	}
 	return defaultCfg, nil
 }
 
+// validate rejects settings that would otherwise be ignored silently.
+func (c *Config) validate() error {
+	for _, pattern := range c.Collectors.System.TopProcesses.Redact {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid collectors.system.topProcesses.redact pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Version returns a short hash of the effective configuration so the server
+// can tell which agents run with changed settings.
+func (c *Config) Version() string {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:6])
+}
+
 func (c *Config) Save(path string) error {
 	v := viper.New()
 	v.SetConfigFile(path)
@@ -199,6 +562,10 @@ func (c *Config) Save(path string) error {
 	v.Set("host.displayName", c.Host.DisplayName)
 	v.Set("host.location", c.Host.Location)
 	v.Set("host.tags", c.Host.Tags)
+	v.Set("host.attributes", c.Host.Attributes)
+	v.Set("host.attributesDir", c.Host.AttributesDir)
+	v.Set("host.attributeCommands", c.Host.AttributeCommands)
+	v.Set("host.attributesRefreshSeconds", c.Host.AttributesRefreshSeconds)
 
 	v.Set("collectors.intervalSeconds", c.Collectors.IntervalSeconds)
 	v.Set("collectors.system.enabled", c.Collectors.System.Enabled)
@@ -207,16 +574,104 @@ func (c *Config) Save(path string) error {
 	v.Set("collectors.system.disk", c.Collectors.System.Disk)
 	v.Set("collectors.system.network", c.Collectors.System.Network)
 	v.Set("collectors.system.eventLog", c.Collectors.System.EventLog)
+	v.Set("collectors.system.psi", c.Collectors.System.PSI)
+	v.Set("collectors.system.kernel", c.Collectors.System.Kernel)
+	v.Set("collectors.system.interfaceExclude", c.Collectors.System.InterfaceExclude)
+	v.Set("collectors.system.diskFilter.includeFsTypes", c.Collectors.System.DiskFilter.IncludeFsTypes)
+	v.Set("collectors.system.diskFilter.excludeFsTypes", c.Collectors.System.DiskFilter.ExcludeFsTypes)
+	v.Set("collectors.system.diskFilter.includeMountPoints", c.Collectors.System.DiskFilter.IncludeMountPoints)
+	v.Set("collectors.system.diskFilter.excludeMountPoints", c.Collectors.System.DiskFilter.ExcludeMountPoints)
+	v.Set("collectors.system.diskFilter.excludeDevices", c.Collectors.System.DiskFilter.ExcludeDevices)
+	v.Set("collectors.system.diskFilter.statTimeoutMs", c.Collectors.System.DiskFilter.StatTimeoutMs)
+	v.Set("collectors.system.topProcesses.enabled", c.Collectors.System.TopProcesses.Enabled)
+	v.Set("collectors.system.topProcesses.count", c.Collectors.System.TopProcesses.Count)
+	v.Set("collectors.system.topProcesses.mode", c.Collectors.System.TopProcesses.Mode)
+	v.Set("collectors.system.topProcesses.cpuThreshold", c.Collectors.System.TopProcesses.CPUThreshold)
+	v.Set("collectors.system.topProcesses.ramThreshold", c.Collectors.System.TopProcesses.RAMThreshold)
+	v.Set("collectors.system.topProcesses.includeCmdline", c.Collectors.System.TopProcesses.IncludeCmdline)
+	v.Set("collectors.system.topProcesses.cmdlineMaxLength", c.Collectors.System.TopProcesses.CmdlineMaxLength)
+	v.Set("collectors.system.topProcesses.redact", c.Collectors.System.TopProcesses.Redact)
+	v.Set("collectors.system.hostMode.enabled", c.Collectors.System.HostMode.Enabled)
+	v.Set("collectors.system.hostMode.proc", c.Collectors.System.HostMode.Proc)
+	v.Set("collectors.system.hostMode.sys", c.Collectors.System.HostMode.Sys)
+	v.Set("collectors.system.hostMode.etc", c.Collectors.System.HostMode.Etc)
+	v.Set("collectors.system.hostMode.root", c.Collectors.System.HostMode.Root)
+	v.Set("collectors.system.publicIp.enabled", c.Collectors.System.PublicIP.Enabled)
+	v.Set("collectors.system.publicIp.ipv6", c.Collectors.System.PublicIP.IPv6)
+	v.Set("collectors.system.publicIp.ttlSeconds", c.Collectors.System.PublicIP.TTLSeconds)
+	v.Set("collectors.system.publicIp.timeoutMs", c.Collectors.System.PublicIP.TimeoutMs)
+	providers := make([]map[string]string, len(c.Collectors.System.PublicIP.Providers))
+	for i, p := range c.Collectors.System.PublicIP.Providers {
+		providers[i] = map[string]string{"type": p.Type, "address": p.Address}
+	}
+	v.Set("collectors.system.publicIp.providers", providers)
+	v.Set("collectors.cloud.enabled", c.Collectors.Cloud.Enabled)
+	v.Set("collectors.cloud.providers", c.Collectors.Cloud.Providers)
+	v.Set("collectors.cloud.timeoutMs", c.Collectors.Cloud.TimeoutMs)
+	v.Set("collectors.cloud.refreshMinutes", c.Collectors.Cloud.RefreshMinutes)
+	v.Set("collectors.cloud.includeTags", c.Collectors.Cloud.IncludeTags)
+	v.Set("collectors.inventory.enabled", c.Collectors.Inventory.Enabled)
+	v.Set("collectors.inventory.refreshMinutes", c.Collectors.Inventory.RefreshMinutes)
+	v.Set("collectors.packages.enabled", c.Collectors.Packages.Enabled)
+	v.Set("collectors.packages.refreshMinutes", c.Collectors.Packages.RefreshMinutes)
+	v.Set("collectors.updates.enabled", c.Collectors.Updates.Enabled)
+	v.Set("collectors.updates.refreshMinutes", c.Collectors.Updates.RefreshMinutes)
+	v.Set("collectors.events.bookmarkPath", c.Collectors.Events.BookmarkPath)
+	v.Set("collectors.events.rateLimitPerMinute", c.Collectors.Events.RateLimitPerMinute)
+
+	v.Set("heartbeat.deltaEnabled", c.Heartbeat.DeltaEnabled)
+	v.Set("heartbeat.fullSnapshotEvery", c.Heartbeat.FullSnapshotEvery)
+	v.Set("heartbeat.streams.enabled", c.Heartbeat.Streams.Enabled)
+	for name, sc := range map[string]StreamConfig{
+		"metrics":   c.Heartbeat.Streams.Metrics,
+		"inventory": c.Heartbeat.Streams.Inventory,
+		"events":    c.Heartbeat.Streams.Events,
+	} {
+		prefix := "heartbeat.streams." + name + "."
+		v.Set(prefix+"path", sc.Path)
+		v.Set(prefix+"queueSize", sc.QueueSize)
+		v.Set(prefix+"maxBatch", sc.MaxBatch)
+		v.Set(prefix+"maxBytes", sc.MaxBytes)
+		v.Set(prefix+"retryCount", sc.RetryCount)
+		v.Set(prefix+"retryDelay", sc.RetryDelay)
+	}
 
 	v.Set("services.windows.enabled", c.Services.Windows.Enabled)
 	v.Set("services.windows.services", c.Services.Windows.Services)
 	v.Set("services.systemd.enabled", c.Services.Systemd.Enabled)
 	v.Set("services.systemd.units", c.Services.Systemd.Units)
+	v.Set("services.systemd.cgroups", c.Services.Systemd.Cgroups)
 	v.Set("services.docker.enabled", c.Services.Docker.Enabled)
 	v.Set("services.docker.containers", c.Services.Docker.Containers)
+	v.Set("services.docker.cgroups", c.Services.Docker.Cgroups)
 	v.Set("services.iis.enabled", c.Services.IIS.Enabled)
 	v.Set("services.iis.sites", c.Services.IIS.Sites)
 	v.Set("services.iis.appPools", c.Services.IIS.AppPools)
+	v.Set("services.mounts.enabled", c.Services.Mounts.Enabled)
+	v.Set("services.mounts.timeoutMs", c.Services.Mounts.TimeoutMs)
+	mounts := make([]map[string]interface{}, len(c.Services.Mounts.Mounts))
+	for i, m := range c.Services.Mounts.Mounts {
+		mounts[i] = map[string]interface{}{
+			"path":      m.Path,
+			"fsType":    m.FsType,
+			"source":    m.Source,
+			"minFreeGb": m.MinFreeGB,
+		}
+	}
+	v.Set("services.mounts.mounts", mounts)
+	v.Set("services.processes.enabled", c.Services.Processes.Enabled)
+	processes := make([]map[string]interface{}, len(c.Services.Processes.Processes))
+	for i, p := range c.Services.Processes.Processes {
+		processes[i] = map[string]interface{}{
+			"name":         p.Name,
+			"processName":  p.ProcessName,
+			"exe":          p.Exe,
+			"cmdline":      p.Cmdline,
+			"user":         p.User,
+			"minInstances": p.MinInstances,
+		}
+	}
+	v.Set("services.processes.processes", processes)
 
 	v.Set("gui.enabled", c.GUI.Enabled)
 	v.Set("gui.startMinimized", c.GUI.StartMinimized)
@@ -226,6 +681,11 @@ func (c *Config) Save(path string) error {
 	v.Set("agent.startWithOS", c.Agent.StartWithOS)
 	v.Set("agent.minimizeToTray", c.Agent.MinimizeToTray)
 	v.Set("agent.checkForUpdates", c.Agent.CheckForUpdates)
+	v.Set("agent.shutdown.notify", c.Agent.Shutdown.Notify)
+	v.Set("agent.shutdown.timeoutSeconds", c.Agent.Shutdown.TimeoutSeconds)
+	v.Set("agent.shutdown.rebootDowntimeSeconds", c.Agent.Shutdown.RebootDowntimeSeconds)
+	v.Set("agent.shutdown.updateDowntimeSeconds", c.Agent.Shutdown.UpdateDowntimeSeconds)
+	v.Set("agent.shutdown.updateMarkerPath", c.Agent.Shutdown.UpdateMarkerPath)
 
 	v.Set("logging.level", c.Logging.Level)
 	v.Set("logging.maxSizeMB", c.Logging.MaxSizeMB)